@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/akquinet/pdnsgrep/edit"
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply -f FILE",
+	Short: "Apply the RRsets of a YAML or JSON file",
+	Long: `Apply makes the RRsets in PowerDNS match the records of a file, a YAML or
+JSON list in the schema of the JSON output. RRsets, records and comments not
+in the file are kept, as a search export only holds the matching records,
+unless --prune is given: it removes the records and comments of the RRsets
+in the file that are not in it and deletes all RRsets at the names of the
+file that are not in it. The plan is shown and applied with one PATCH
+request per zone after confirmation. SOA records are skipped.`,
+	Example: `pdnsgrep zone export example.com. -o json > records.json
+pdnsgrep apply -f records.json --dry-run
+pdnsgrep apply -f records.yaml --prune`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file := viper.GetString("file")
+		if file == "" {
+			log.Fatal("a file is required, use -f")
+		}
+		// stdin can't answer the confirmation once the records are read
+		if file == "-" && !viper.GetBool("yes") && !viper.GetBool("dry-run") {
+			log.Fatal("reading the records from stdin requires --yes or --dry-run")
+		}
+
+		initConfig()
+		client := createPDNSClient()
+
+		changes, err := planChanges(client, file, viper.GetBool("prune"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := applyChanges(client, changes, applyOptions{plan: true}); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// planChanges returns the changes making the zones match the records in
+// file. "-" reads the records from stdin. Records without a zone belong to
+// the longest matching zone of the server.
+func planChanges(client *pdns.PDNSAPI, file string, prune bool) ([]pdns.RRSetChange, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	records, err := edit.ParseRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []pdns.Zone
+	for i, r := range records {
+		if r.Zone != "" || r.Name == "" {
+			continue
+		}
+		if zones == nil {
+			if zones, err = client.ListZones(context.Background()); err != nil {
+				return nil, err
+			}
+		}
+		zone, ok := pdns.FindZone(zones, pdns.CanonicalName(r.Name))
+		if !ok {
+			return nil, fmt.Errorf("no zone found for %s", r.Name)
+		}
+		records[i].Zone = zone.Name
+	}
+
+	desired, err := edit.DesiredRRSets(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []pdns.RRSetChange
+	for _, zoneName := range slices.SortedFunc(maps.Keys(desired), misc.CompareDNSNames) {
+		zone, err := client.GetZone(zoneName)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, edit.Plan(zone, desired[zoneName], prune)...)
+	}
+	return changes, nil
+}
+
+func init() {
+	addApplyFlags(applyCmd.Flags())
+	applyCmd.Flags().StringP("file", "f", "", `YAML or JSON file with the records, "-" for stdin`)
+	applyCmd.Flags().Bool("prune", false, "remove records, comments and RRsets at the names of the file that are not in it")
+	rootCmd.AddCommand(applyCmd)
+}
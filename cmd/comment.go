@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var commentCmd = &cobra.Command{
+	Use:   "comment",
+	Short: "Manage the comments of RRsets",
+}
+
+var commentAddCmd = &cobra.Command{
+	Use:   "add NAME TYPE TEXT",
+	Short: "Add a comment to an RRset",
+	Long: `Add adds a comment to an RRset and keeps its other comments. The account of
+the comment is taken from account in the config, by default the user name.`,
+	Example: `pdnsgrep comment add fw-1.example.com. A "primary firewall, owned by netops"`,
+	Args:    cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		client := createPDNSClient()
+
+		zone, before := loadRRSet(client, args[0], args[1])
+		after := before
+		after.Comments = append(slices.Clone(before.Comments), newComment(args[2]))
+
+		change := pdns.RRSetChange{Zone: zone.Name, Before: &before, After: &after}
+		if err := applyChanges(client, []pdns.RRSetChange{change}, applyOptions{}); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commentListCmd = &cobra.Command{
+	Use:   "list SEARCH...",
+	Short: "List the records found by the search that have comments",
+	Example: `pdnsgrep comment list "*fw*"
+pdnsgrep comment list example.com. -o json`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		if !viper.GetBool("no-highlight") {
+			misc.SetHighlightPatterns(args)
+		}
+		client := createPDNSClient()
+
+		ctx := context.Background()
+		found, err := fetchRecords(ctx, client, args, "record", viper.GetString("type"), viper.GetString("sort-by"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := pdns.AddDetails(ctx, client, found); err != nil {
+			log.Fatal(err)
+		}
+		found = slices.DeleteFunc(found, func(r pdns.PDNSSearchResponseItem) bool { return len(r.Comments) == 0 })
+
+		if len(found) == 0 {
+			fmt.Println("Nothing found")
+			os.Exit(0)
+		}
+		outputResults(found)
+	},
+}
+
+var commentDeleteCmd = &cobra.Command{
+	Use:   "delete NAME TYPE [TEXT]",
+	Short: "Delete comments of an RRset",
+	Long: `Delete removes the comments of an RRset with the given text. The other
+comments are kept. With --all all comments of the RRset are removed.`,
+	Example: `pdnsgrep comment delete fw-1.example.com. A "primary firewall, owned by netops"
+pdnsgrep comment delete fw-1.example.com. A --all`,
+	Args: cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		all := viper.GetBool("all")
+		if all == (len(args) == 3) {
+			log.Fatal("either a comment text or --all is required")
+		}
+
+		initConfig()
+		client := createPDNSClient()
+
+		zone, before := loadRRSet(client, args[0], args[1])
+		after := before
+		after.Comments = []pdns.Comment{}
+		if !all {
+			for _, c := range before.Comments {
+				if c.Content != args[2] {
+					after.Comments = append(after.Comments, c)
+				}
+			}
+		}
+		if len(after.Comments) == len(before.Comments) {
+			log.Fatalf("no matching comment found on %s %s", before.Name, before.Type)
+		}
+
+		change := pdns.RRSetChange{Zone: zone.Name, Before: &before, After: &after}
+		if err := applyChanges(client, []pdns.RRSetChange{change}, applyOptions{}); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// loadRRSet returns the RRset of name and type with its zone.
+func loadRRSet(client *pdns.PDNSAPI, name, rType string) (*pdns.Zone, pdns.RRSet) {
+	name = pdns.CanonicalName(name)
+	rType = strings.ToUpper(rType)
+	zone, err := loadZoneOf(client, name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rrset, ok := zone.FindRRSet(name, rType)
+	if !ok {
+		log.Fatalf("no %s RRset found for %s in zone %s", rType, name, zone.Name)
+	}
+	return zone, rrset
+}
+
+// commentAccount returns the account of new comments, account in the config
+// or else the user name.
+func commentAccount() string {
+	if account := viper.GetString("account"); account != "" {
+		return account
+	}
+	return currentUser()
+}
+
+func newComment(content string) pdns.Comment {
+	return pdns.Comment{Content: content, Account: commentAccount(), ModifiedAt: time.Now().Unix()}
+}
+
+func init() {
+	addEditFlags(commentAddCmd.Flags())
+	addEditFlags(commentDeleteCmd.Flags())
+	commentDeleteCmd.Flags().Bool("all", false, "delete all comments of the RRset")
+	addOutputFlags(commentListCmd.Flags())
+	commentListCmd.Flags().StringP("type", "t", "", "filter type of record (A, AAAA, TXT ....)")
+	commentCmd.AddCommand(commentAddCmd)
+	commentCmd.AddCommand(commentListCmd)
+	commentCmd.AddCommand(commentDeleteCmd)
+	rootCmd.AddCommand(commentCmd)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/akquinet/pdnsgrep/edit"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var disableCmd = &cobra.Command{
+	Use:   "disable SEARCH...",
+	Short: "Disable the records found by the search",
+	Long: `Disable sets the disabled flag of the records found by the search, so
+PowerDNS stops serving them without deleting them. A comment on the RRset
+records who disabled the records and the --reason.`,
+	Example: `pdnsgrep disable fw-1.example.com. --reason "INC-1234 firewall down"
+pdnsgrep disable 10.0.0.1 --type A --dry-run`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		toggleRecords(args, true)
+	},
+}
+
+var enableCmd = &cobra.Command{
+	Use:   "enable SEARCH...",
+	Short: "Enable the disabled records found by the search",
+	Long: `Enable clears the disabled flag of the records found by the search. Once all
+records of an RRset are enabled, the comments added by disable are removed.
+With --reason a comment records who enabled the records and why.`,
+	Example: `pdnsgrep enable fw-1.example.com.`,
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		toggleRecords(args, false)
+	},
+}
+
+// toggleRecords sets the disabled flag of the records found by the search
+// for terms.
+func toggleRecords(terms []string, disabled bool) {
+	initConfig()
+	client := createPDNSClient()
+
+	changes, err := disabledChanges(client, terms, disabled, viper.GetString("reason"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := applyChanges(client, changes, applyOptions{}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// disabledChanges returns the changes setting the disabled flag of the
+// records found by the search for terms. Other records of their RRsets are
+// left as they are.
+func disabledChanges(client *pdns.PDNSAPI, terms []string, disabled bool, reason string) ([]pdns.RRSetChange, error) {
+	type recordKey struct {
+		rrsetKey
+		content string
+	}
+	matched := make(map[recordKey]bool)
+	found, err := searchRRSets(client, terms, func(r pdns.PDNSSearchResponseItem) bool {
+		matched[recordKey{rrsetKey{r.Zone, r.Name, r.Type}, r.Content}] = true
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	toggle := edit.Toggle{Disabled: disabled, User: currentUser(), Reason: reason, Account: commentAccount(), Time: time.Now()}
+	var changes []pdns.RRSetChange
+	for _, f := range found {
+		key := rrsetKey{f.zone.Name, f.rrset.Name, f.rrset.Type}
+		change, ok := toggle.Change(f.zone.Name, f.rrset, func(r pdns.Record) bool {
+			return matched[recordKey{key, r.Content}]
+		})
+		if ok {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// addToggleFlags adds the flags of disable and enable.
+func addToggleFlags(flags *pflag.FlagSet) {
+	addEditFlags(flags)
+	flags.Lookup("zone").Usage = "only change records of this zone"
+	flags.StringP("type", "t", "", "only change records of this type (A, AAAA, TXT ....)")
+	flags.String("reason", "", "reason recorded in the comment of the RRset")
+}
+
+func init() {
+	addToggleFlags(disableCmd.Flags())
+	addToggleFlags(enableCmd.Flags())
+	rootCmd.AddCommand(disableCmd)
+	rootCmd.AddCommand(enableCmd)
+}
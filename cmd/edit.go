@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set NAME TYPE TTL CONTENT [CONTENT...]",
+	Short: "Create or replace an RRset",
+	Example: `pdnsgrep set fw-1.example.com. A 300 10.0.0.1 10.0.0.2
+pdnsgrep set www.example.com. CNAME 3600 fw-1.example.com. --dry-run`,
+	Args: cobra.MinimumNArgs(4),
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		client := createPDNSClient()
+
+		name := pdns.CanonicalName(args[0])
+		rType := strings.ToUpper(args[1])
+		ttl, err := strconv.Atoi(args[2])
+		if err != nil || ttl < 0 {
+			log.Fatalf("invalid TTL %s", args[2])
+		}
+
+		zone, err := loadZoneOf(client, name)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		after := &pdns.RRSet{Name: name, Type: rType, TTL: ttl}
+		for _, content := range args[3:] {
+			after.Records = append(after.Records, pdns.Record{Content: content})
+		}
+		change := pdns.RRSetChange{Zone: zone.Name, After: after}
+		if before, ok := zone.FindRRSet(name, rType); ok {
+			// the comments are kept by the patch
+			kept := *after
+			kept.Comments = before.Comments
+			if before.Equal(kept) {
+				fmt.Println("No changes")
+				return
+			}
+			change.Before = &before
+		}
+
+		if err := applyChanges(client, []pdns.RRSetChange{change}, applyOptions{}); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var deleteCmd = &cobra.Command{
+	Use:     "delete NAME TYPE",
+	Short:   "Delete an RRset",
+	Example: `pdnsgrep delete old.example.com. CNAME`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		client := createPDNSClient()
+
+		name := pdns.CanonicalName(args[0])
+		rType := strings.ToUpper(args[1])
+		zone, err := loadZoneOf(client, name)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		before, ok := zone.FindRRSet(name, rType)
+		if !ok {
+			log.Fatalf("no %s RRset found for %s in zone %s", rType, name, zone.Name)
+		}
+
+		change := pdns.RRSetChange{Zone: zone.Name, Before: &before}
+		if err := applyChanges(client, []pdns.RRSetChange{change}, applyOptions{}); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// loadZoneOf returns the zone given with --zone or else the longest zone
+// of the server that name is part of.
+func loadZoneOf(client *pdns.PDNSAPI, name string) (*pdns.Zone, error) {
+	zoneName := viper.GetString("zone")
+	if zoneName == "" {
+		zones, err := client.ListZones(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		zone, ok := pdns.FindZone(zones, name)
+		if !ok {
+			return nil, fmt.Errorf("no zone found for %s", name)
+		}
+		zoneName = zone.Name
+	} else if _, ok := pdns.FindZone([]pdns.Zone{{Name: zoneName}}, name); !ok {
+		return nil, fmt.Errorf("%s is not part of zone %s", name, zoneName)
+	}
+	return client.GetZone(zoneName)
+}
+
+// rrsetKey identifies an RRset of a zone.
+type rrsetKey struct {
+	zone, name, rType string
+}
+
+// zoneRRSet is an RRset with the zone it belongs to.
+type zoneRRSet struct {
+	zone  *pdns.Zone
+	rrset pdns.RRSet
+}
+
+// searchRRSets searches for terms and returns the RRsets of the found records
+// for which match returns true, ordered by zone, name and type. The RRsets
+// are taken from their zones, so records of an RRset the search didn't
+// return are kept. --type and --zone restrict the records.
+func searchRRSets(client *pdns.PDNSAPI, terms []string, match func(pdns.PDNSSearchResponseItem) bool) ([]zoneRRSet, error) {
+	found, err := fetchRecords(context.Background(), client, terms, "record", viper.GetString("type"), "zone,name,type")
+	if err != nil {
+		return nil, err
+	}
+
+	onlyZone := viper.GetString("zone")
+	var zones []string
+	affected := make(map[rrsetKey]bool)
+	for _, r := range found {
+		if onlyZone != "" && !strings.EqualFold(r.Zone, pdns.CanonicalName(onlyZone)) {
+			continue
+		}
+		if match != nil && !match(r) {
+			continue
+		}
+		if !slices.Contains(zones, r.Zone) {
+			zones = append(zones, r.Zone)
+		}
+		affected[rrsetKey{r.Zone, r.Name, r.Type}] = true
+	}
+
+	var rrsets []zoneRRSet
+	for _, zoneName := range zones {
+		zone, err := client.GetZone(zoneName)
+		if err != nil {
+			return nil, err
+		}
+		var inZone []pdns.RRSet
+		for _, rrset := range zone.RRSets {
+			if affected[rrsetKey{zoneName, rrset.Name, rrset.Type}] {
+				inZone = append(inZone, rrset)
+			}
+		}
+		slices.SortFunc(inZone, func(a, b pdns.RRSet) int {
+			if c := misc.CompareDNSNames(a.Name, b.Name); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Type, b.Type)
+		})
+		for _, rrset := range inZone {
+			rrsets = append(rrsets, zoneRRSet{zone: zone, rrset: rrset})
+		}
+	}
+	return rrsets, nil
+}
+
+// changesToRecordChanges converts the RRset changes for diff output. An
+// RRset without comments after the change keeps its comments.
+func changesToRecordChanges(changes []pdns.RRSetChange) []misc.RecordChange {
+	var before, after []pdns.PDNSSearchResponseItem
+	for _, c := range changes {
+		if c.Before != nil {
+			before = append(before, c.Before.Items(c.Zone)...)
+		}
+		if c.After != nil {
+			rrset := *c.After
+			if rrset.Comments == nil && c.Before != nil {
+				rrset.Comments = c.Before.Comments
+			}
+			after = append(after, rrset.Items(c.Zone)...)
+		}
+	}
+	return misc.DiffRRsets(before, after)
+}
+
+// applyOptions adjusts applyChanges for a command.
+type applyOptions struct {
+	// beforePatch is called after the confirmation and stops the changes on
+	// error.
+	beforePatch func() error
+	// undoes is the ID of the journal entry the changes undo.
+	undoes int
+	// plan prints the number of added, changed and deleted RRsets after the
+	// diff.
+	plan bool
+}
+
+// applyChanges shows the changes and applies them with one PATCH request
+// per zone after confirmation. A failing zone doesn't stop the others, the
+// result of every zone is printed at the end and the applied changes are
+// recorded in the journal. With --dry-run the requests are only printed.
+func applyChanges(client *pdns.PDNSAPI, changes []pdns.RRSetChange, opts applyOptions) error {
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return nil
+	}
+	misc.OutputDiff(changesToRecordChanges(changes))
+	if opts.plan {
+		outputPlanSummary(changes)
+	}
+
+	var zones []string
+	byZone := make(map[string][]pdns.RRSetChange)
+	for _, c := range changes {
+		if _, ok := byZone[c.Zone]; !ok {
+			zones = append(zones, c.Zone)
+		}
+		byZone[c.Zone] = append(byZone[c.Zone], c)
+	}
+
+	if viper.GetBool("dry-run") {
+		for _, zone := range zones {
+			output, err := json.MarshalIndent(pdns.NewZonePatch(byZone[zone]), "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling patch: %w", err)
+			}
+			fmt.Printf("\nPATCH %s\n%s\n", client.ZoneURL(zone), output)
+		}
+		return nil
+	}
+
+	question := fmt.Sprintf("Apply %d RRset changes to zone %s?", len(changes), zones[0])
+	if len(zones) > 1 {
+		question = fmt.Sprintf("Apply %d RRset changes to %d zones?", len(changes), len(zones))
+	}
+	if !viper.GetBool("yes") && !confirm(question) {
+		return errors.New("aborted")
+	}
+	if opts.beforePatch != nil {
+		if err := opts.beforePatch(); err != nil {
+			return err
+		}
+	}
+
+	results := make([]misc.ZoneResult, 0, len(zones))
+	var applied []pdns.RRSetChange
+	failed := 0
+	for _, zone := range zones {
+		err := client.PatchZone(zone, pdns.NewZonePatch(byZone[zone]))
+		if err != nil {
+			failed++
+		} else {
+			applied = append(applied, byZone[zone]...)
+		}
+		results = append(results, misc.ZoneResult{Zone: zone, Changes: len(byZone[zone]), Err: err})
+	}
+	misc.OutputZoneResults(results)
+	recordJournal(applied, opts.undoes)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d zones failed", failed, len(zones))
+	}
+	return nil
+}
+
+// outputPlanSummary prints the number of added, changed and deleted RRsets.
+func outputPlanSummary(changes []pdns.RRSetChange) {
+	var add, change, destroy int
+	for _, c := range changes {
+		switch {
+		case c.Before == nil:
+			add++
+		case c.After == nil:
+			destroy++
+		default:
+			change++
+		}
+	}
+	fmt.Printf("\nPlan: %d to add, %d to change, %d to destroy.\n", add, change, destroy)
+}
+
+// confirm asks the question on stderr and reports whether it was answered
+// with yes on stdin.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// addApplyFlags adds the flags of applyChanges.
+func addApplyFlags(flags *pflag.FlagSet) {
+	flags.Bool("dry-run", false, "show the changes and PATCH requests without applying them")
+	flags.BoolP("yes", "y", false, "apply the changes without confirmation")
+}
+
+// addEditFlags adds the flags of commands changing records.
+func addEditFlags(flags *pflag.FlagSet) {
+	addApplyFlags(flags)
+	flags.String("zone", "", "zone of the records (default: longest matching zone)")
+}
+
+func init() {
+	addEditFlags(setCmd.Flags())
+	addEditFlags(deleteCmd.Flags())
+	rootCmd.AddCommand(setCmd)
+	rootCmd.AddCommand(deleteCmd)
+}
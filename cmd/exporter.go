@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/exporter"
+	"github.com/akquinet/pdnsgrep/watch"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var exporterCmd = &cobra.Command{
+	Use:   "exporter [SEARCH...]",
+	Short: "Serve search results as Prometheus metrics",
+	Example: `pdnsgrep exporter --listen :9253 --watch-config queries.yaml
+pdnsgrep exporter --interval 5m "*fw*"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configFile := viper.GetString("watch-config")
+		if configFile == "" && len(args) == 0 {
+			log.Fatal("at least one search term or --watch-config is required")
+		}
+		if configFile != "" && len(args) > 0 {
+			log.Fatal("search terms can't be combined with --watch-config")
+		}
+
+		initConfig()
+		client := createPDNSClient()
+
+		// also the interval of queries without their own
+		interval := viper.GetDuration("interval")
+		if interval <= 0 {
+			log.Fatalf("invalid interval %s, --interval must be greater than 0", interval)
+		}
+		config := &watch.Config{
+			Concurrency: 1,
+			Queries: []watch.Query{{
+				Name:       strings.Join(args, " "),
+				Terms:      args,
+				Type:       viper.GetString("type"),
+				ObjectType: resolveObjectType(),
+				Interval:   interval,
+			}},
+		}
+		if configFile != "" {
+			var err error
+			config, err = watch.LoadConfig(configFile, interval)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		e := &exporter.Exporter{Client: client, Queries: config.Queries, Concurrency: config.Concurrency}
+		os.Exit(serveMetrics(e, viper.GetString("listen")))
+	},
+}
+
+// serveMetrics polls the queries of e and serves their metrics on addr
+// until interrupted.
+func serveMetrics(e *exporter.Exporter, addr string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<html><body><a href="/metrics">Metrics</a></body></html>`))
+	})
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go e.Run(ctx)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Infof("serving metrics on %s/metrics", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error(err)
+		return 1
+	}
+	return 0
+}
+
+func init() {
+	addSearchFlags(exporterCmd.Flags())
+	exporterCmd.Flags().String("listen", ":9253", "address to serve the metrics on")
+	exporterCmd.Flags().Duration("interval", time.Minute, "interval between searches of queries without their own interval")
+	exporterCmd.Flags().String("watch-config", "", "file with named queries to export, see watch --watch-config")
+	rootCmd.AddCommand(exporterCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/watch"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var historyCmd = &cobra.Command{
+	Use:     "history NAME",
+	Short:   "Show how a record changed over time, based on the event log of watch mode",
+	Example: `pdnsgrep history --log events.ndjson fw-1.example.com.`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+
+		logFile := viper.GetString("log")
+		if logFile == "" {
+			log.Fatal("the event log needs to be defined with --log")
+		}
+
+		events, err := watch.ReadEvents(logFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		events = watch.FilterEvents(events, args[0])
+
+		if viper.GetString("output") == "json" {
+			if events == nil {
+				events = []watch.ChangeEvent{}
+			}
+			output, err := json.MarshalIndent(events, "", "  ")
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(string(output))
+			return
+		}
+
+		if len(events) == 0 {
+			fmt.Println("No changes recorded")
+			os.Exit(0)
+		}
+		outputHistory(events)
+	},
+}
+
+// outputHistory prints the events grouped by the time they were recorded
+// and the query they belong to.
+func outputHistory(events []watch.ChangeEvent) {
+	for i := 0; i < len(events); {
+		at, query := events[i].Time, events[i].Query
+		var changes []misc.RecordChange
+		for ; i < len(events) && events[i].Time.Equal(at) && events[i].Query == query; i++ {
+			changes = append(changes, events[i].RecordChange)
+		}
+		header := at.Local().Format("2006-01-02 15:04:05")
+		if query != "" {
+			header += " [" + query + "]"
+		}
+		fmt.Printf("=== %s ===\n", header)
+		misc.OutputDiff(changes)
+	}
+}
+
+func init() {
+	historyCmd.Flags().String("log", "", "event log written by watch mode")
+	historyCmd.Flags().StringP("output", "o", "table", "output (table|json)")
+	rootCmd.AddCommand(historyCmd)
+}
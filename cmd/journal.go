@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/edit"
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [ID]",
+	Short: "Undo a change recorded in the journal",
+	Long: `Undo restores the RRsets changed by a journal entry, by default the latest
+one not undone yet. Repeated undos step back through the journal. RRsets
+changed again since are reported as conflicts, --force undoes them anyway.`,
+	Example: `pdnsgrep undo
+pdnsgrep undo 12 --dry-run`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		client := createPDNSClient()
+
+		entry, err := undoEntry(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		changes, err := undoChanges(client, entry)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := applyChanges(client, changes, applyOptions{undoes: entry.ID}); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var journalCmd = &cobra.Command{
+	Use:   "journal",
+	Short: "Show the journal of changed records",
+}
+
+var journalListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the latest journal entries with their diff",
+	Example: `pdnsgrep journal list -n 3`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+		entries, err := openJournal().Entries()
+		if err != nil {
+			log.Fatal(err)
+		}
+		undoneBy := undoneEntries(entries)
+		if n := viper.GetInt("count"); n > 0 && len(entries) > n {
+			entries = entries[len(entries)-n:]
+		}
+		for i, e := range entries {
+			if i > 0 {
+				fmt.Println()
+			}
+			outputJournalEntry(e, undoneBy[e.ID])
+		}
+	},
+}
+
+var journalShowCmd = &cobra.Command{
+	Use:     "show ID",
+	Short:   "Show a journal entry with its diff",
+	Example: `pdnsgrep journal show 12`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		loadConfig()
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.Fatalf("invalid journal entry ID %s", args[0])
+		}
+		journal := openJournal()
+		entries, err := journal.Entries()
+		if err != nil {
+			log.Fatal(err)
+		}
+		entry, err := journal.Entry(id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		outputJournalEntry(*entry, undoneEntries(entries)[id])
+	},
+}
+
+// openJournal returns the journal configured with journal, by default in
+// $XDG_STATE_HOME.
+func openJournal() *edit.Journal {
+	path := viper.GetString("journal")
+	if path == "" {
+		var err error
+		path, err = edit.DefaultJournalPath()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	return &edit.Journal{Path: path}
+}
+
+// recordJournal appends the applied changes to the journal. The changes are
+// applied already, so a failure is only logged.
+func recordJournal(changes []pdns.RRSetChange, undoes int) {
+	if len(changes) == 0 {
+		return
+	}
+	entry := edit.JournalEntry{
+		Time:    time.Now(),
+		User:    currentUser(),
+		Command: journalCommand(os.Args),
+		Undoes:  undoes,
+		Changes: changes,
+	}
+	id, err := openJournal().Append(entry)
+	if err != nil {
+		log.Errorf("recording changes in journal: %v", err)
+		return
+	}
+	log.Infof("recorded changes as journal entry %d", id)
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// journalCommand returns the command line for the journal with the value of
+// --token masked.
+func journalCommand(args []string) string {
+	masked := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case i > 0 && args[i-1] == "--token":
+			arg = "***"
+		case strings.HasPrefix(arg, "--token="):
+			arg = "--token=***"
+		}
+		masked[i] = arg
+	}
+	return strings.Join(masked, " ")
+}
+
+// undoneEntries maps the IDs of undone entries to the IDs of their undos.
+func undoneEntries(entries []edit.JournalEntry) map[int]int {
+	undoneBy := make(map[int]int)
+	for _, e := range entries {
+		if e.Undoes != 0 {
+			undoneBy[e.Undoes] = e.ID
+		}
+	}
+	return undoneBy
+}
+
+// undoEntry returns the journal entry with the ID in args, or else the
+// latest entry not undone yet.
+func undoEntry(args []string) (*edit.JournalEntry, error) {
+	journal := openJournal()
+	entries, err := journal.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		entry, ok := edit.LastUndoable(entries)
+		if !ok {
+			return nil, fmt.Errorf("nothing to undo in %s", journal.Path)
+		}
+		return entry, nil
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid journal entry ID %s", args[0])
+	}
+	if by, ok := undoneEntries(entries)[id]; ok && !viper.GetBool("force") {
+		return nil, fmt.Errorf("journal entry %d was undone by entry %d already, use --force to undo it again", id, by)
+	}
+	return journal.Entry(id)
+}
+
+// undoChanges returns the changes restoring the RRsets before the entry.
+// The diff is made against the current RRsets. RRsets changed since the
+// entry are conflicts, which fail unless --force is given.
+func undoChanges(client *pdns.PDNSAPI, entry *edit.JournalEntry) ([]pdns.RRSetChange, error) {
+	zones := make(map[string]*pdns.Zone)
+	for _, name := range entry.Zones() {
+		zone, err := client.GetZone(name)
+		if err != nil {
+			return nil, err
+		}
+		zones[name] = zone
+	}
+
+	changes, conflicts, err := entry.UndoChanges(zones)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range conflicts {
+		rrset := c.Before
+		if rrset == nil {
+			rrset = c.After
+		}
+		log.Warnf("%s %s in zone %s was changed after journal entry %d", rrset.Name, rrset.Type, c.Zone, entry.ID)
+	}
+	if len(conflicts) > 0 && !viper.GetBool("force") {
+		return nil, fmt.Errorf("%d RRsets were changed after journal entry %d, use --force to undo anyway", len(conflicts), entry.ID)
+	}
+	return changes, nil
+}
+
+// outputJournalEntry prints the entry with its diff.
+func outputJournalEntry(e edit.JournalEntry, undoneBy int) {
+	header := fmt.Sprintf("#%d %s %s: %s", e.ID, e.Time.Local().Format(time.DateTime), e.User, e.Command)
+	if e.Undoes != 0 {
+		header += fmt.Sprintf(" (undoes #%d)", e.Undoes)
+	}
+	if undoneBy != 0 {
+		header += fmt.Sprintf(" (undone by #%d)", undoneBy)
+	}
+	fmt.Println(header)
+	misc.OutputDiff(changesToRecordChanges(e.Changes))
+}
+
+func init() {
+	addApplyFlags(undoCmd.Flags())
+	undoCmd.Flags().Bool("force", false, "undo RRsets changed since and entries undone already")
+	journalListCmd.Flags().IntP("count", "n", 10, "number of entries to show (0 = all)")
+	journalCmd.AddCommand(journalListCmd)
+	journalCmd.AddCommand(journalShowCmd)
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(journalCmd)
+}
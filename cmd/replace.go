@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var replaceCmd = &cobra.Command{
+	Use:   "replace OLD NEW",
+	Short: "Replace a name or address in all records referencing it",
+	Long: `Replace searches for records containing OLD and replaces it with NEW in
+their contents and names. Only whole names and addresses are replaced, so
+10.0.0.1 doesn't match 10.0.0.10. Renamed RRsets are deleted and created with
+the new name. The case of names is ignored, but not in TXT records. The
+changes are applied with one PATCH request per zone.`,
+	Example: `pdnsgrep replace 10.0.0.1 10.1.0.1 --type A
+pdnsgrep replace fw-1.example.com. fw-2.example.com. --content-only --dry-run`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		client := createPDNSClient()
+
+		changes, err := replaceChanges(client, args[0], args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := applyChanges(client, changes, applyOptions{}); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// replaceChanges searches for old and returns the changes replacing it with
+// new in the found RRsets.
+func replaceChanges(client *pdns.PDNSAPI, old, new string) ([]pdns.RRSetChange, error) {
+	contentOnly := viper.GetBool("content-only")
+	term := "*" + strings.TrimSuffix(old, ".") + "*"
+	found, err := searchRRSets(client, []string{term}, func(r pdns.PDNSSearchResponseItem) bool {
+		_, inContent := misc.ReplaceBounded(r.Content, old, new, foldsContent(r.Type))
+		_, inName := misc.ReplaceBounded(r.Name, old, new, true)
+		return inContent > 0 || (!contentOnly && inName > 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []pdns.RRSetChange
+	for _, f := range found {
+		changes = append(changes, replaceInRRSet(f.zone, f.rrset, old, new, contentOnly)...)
+	}
+	return changes, nil
+}
+
+// foldsContent reports whether the case is ignored when replacing in the
+// contents of rType. Contents are names and addresses, except for the free
+// text of TXT and SPF records.
+func foldsContent(rType string) bool {
+	return rType != "TXT" && rType != "SPF"
+}
+
+// replaceInRRSet returns the changes replacing old with new in the contents
+// and, unless contentOnly, the name of rrset. A renamed RRset is deleted and
+// created with the new name and its comments. It is skipped with a warning
+// if the new name is outside of the zone or already has an RRset of the
+// type.
+func replaceInRRSet(zone *pdns.Zone, rrset pdns.RRSet, old, new string, contentOnly bool) []pdns.RRSetChange {
+	after := rrset
+	after.Records = slices.Clone(rrset.Records)
+	after.Comments = nil
+	for i, r := range after.Records {
+		after.Records[i].Content, _ = misc.ReplaceBounded(r.Content, old, new, foldsContent(rrset.Type))
+	}
+
+	if !contentOnly {
+		name, n := misc.ReplaceBounded(rrset.Name, old, new, true)
+		if n > 0 {
+			name = pdns.CanonicalName(name)
+			if _, ok := pdns.FindZone([]pdns.Zone{*zone}, name); !ok {
+				log.Warnf("skipping %s %s: %s is not part of zone %s", rrset.Name, rrset.Type, name, zone.Name)
+				return nil
+			}
+			if _, ok := zone.FindRRSet(name, rrset.Type); ok {
+				log.Warnf("skipping %s %s: %s already has a %s RRset", rrset.Name, rrset.Type, name, rrset.Type)
+				return nil
+			}
+			after.Name = name
+			after.Comments = rrset.Comments
+			return []pdns.RRSetChange{
+				{Zone: zone.Name, Before: &rrset},
+				{Zone: zone.Name, After: &after},
+			}
+		}
+	}
+
+	kept := after
+	kept.Comments = rrset.Comments
+	if rrset.Equal(kept) {
+		return nil
+	}
+	return []pdns.RRSetChange{{Zone: zone.Name, Before: &rrset, After: &after}}
+}
+
+func init() {
+	addEditFlags(replaceCmd.Flags())
+	replaceCmd.Flags().Lookup("zone").Usage = "only replace in records of this zone"
+	replaceCmd.Flags().StringP("type", "t", "", "only replace in RRsets of this type (A, AAAA, TXT ....)")
+	replaceCmd.Flags().Bool("content-only", false, "only replace in record contents, not in names")
+	rootCmd.AddCommand(replaceCmd)
+}
@@ -8,9 +8,10 @@ import (
 
 	"github.com/akquinet/pdnsgrep/misc"
 	"github.com/akquinet/pdnsgrep/pdns"
-	"github.com/fatih/color"
+	"github.com/akquinet/pdnsgrep/watch"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	log "github.com/sirupsen/logrus"
@@ -23,6 +24,11 @@ var rootCmd = &cobra.Command{
 	Example:               "pdnsgrep \"*firewall*\"",
 	DisableFlagsInUseLine: true,
 	Args:                  cobra.MinimumNArgs(1),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// bind the flags of the executed command, subcommands define some
+		// flags with the same name
+		viper.BindPFlags(cmd.Flags())
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		completion := viper.GetString("show-completion")
 		if completion != "" {
@@ -30,20 +36,20 @@ var rootCmd = &cobra.Command{
 			os.Exit(0)
 		}
 
-		initConfig()
 		// We don't need to check for empty args anymore since we've set MinimumNArgs(1)
-
-		client := createPDNSClient()
-		objectType := resolveObjectType()
+		client, objectType := prepareSearch(args)
 
 		// Auto-enable watch if any watch sub-flags are used
-		if viper.GetBool("watch-changes") || viper.IsSet("watch-interval") || viper.GetBool("watch-clear") {
+		if viper.GetBool("watch-changes") || viper.IsSet("watch-interval") || viper.GetBool("watch-clear") ||
+			viper.GetString("on-change-exec") != "" || viper.GetString("on-change-webhook") != "" ||
+			viper.GetString("log") != "" ||
+			viper.GetBool("until-found") || viper.GetBool("until-gone") || viper.GetBool("until-changed") ||
+			viper.GetDuration("watch-timeout") > 0 {
 			viper.Set("watch", true)
 		}
 
 		if viper.GetBool("watch") {
-			watchMode(client, args, objectType)
-			return
+			os.Exit(watchMode(client, args, objectType))
 		}
 
 		ctx := context.Background()
@@ -58,7 +64,10 @@ var rootCmd = &cobra.Command{
 		}
 
 		if viper.GetBool("stats") {
-			misc.OutputStats(found)
+			stats := misc.ComputeStats(found, viper.GetInt("top"))
+			if err := misc.OutputStats(stats, viper.GetString("stats-format"), viper.GetString("delimiter")); err != nil {
+				log.Fatal(err)
+			}
 			return
 		}
 
@@ -67,6 +76,15 @@ var rootCmd = &cobra.Command{
 }
 
 func outputResults(records []pdns.PDNSSearchResponseItem) {
+	if groupBy := viper.GetString("group-by"); groupBy != "" {
+		err := misc.OutputGrouped(records, groupBy, viper.GetString("output"), viper.GetString("delimiter"))
+		if err != nil {
+			log.Error(err)
+			log.Exit(1)
+		}
+		return
+	}
+
 	switch viper.GetString("output") {
 	case "table":
 		misc.OutputToTable(records)
@@ -76,97 +94,86 @@ func outputResults(records []pdns.PDNSSearchResponseItem) {
 		misc.OutputToStdout(records)
 	case "json":
 		misc.OutputToJSON(records)
+	case "tree":
+		misc.OutputToTree(records)
 	default:
 		log.Errorf("Output format %s not known\n", viper.GetString("output"))
 		log.Exit(1)
 	}
 }
 
+// prepareSearch loads the config and returns the client and object type
+// for searching args.
+func prepareSearch(args []string) (*pdns.PDNSAPI, string) {
+	initConfig()
+	if !viper.GetBool("no-highlight") {
+		misc.SetHighlightPatterns(args)
+	}
+	return createPDNSClient(), resolveObjectType()
+}
+
 func fetchAndProcessRecords(ctx context.Context, client *pdns.PDNSAPI, args []string, objectType string) ([]pdns.PDNSSearchResponseItem, error) {
-	found, err := pdns.GetPDNSRecords(ctx, client, args, objectType)
+	found, err := fetchRecords(ctx, client, args, objectType, viper.GetString("type"), viper.GetString("sort-by"))
 	if err != nil {
 		return nil, err
 	}
 
-	rType := viper.GetString("type")
-	if rType != "" {
-		found = pdns.FilterRecordsOnType(found, rType)
+	found, err = addDetails(ctx, client, found)
+	if err != nil {
+		return nil, err
 	}
-
-	sortBy := viper.GetString("sort-by")
-	if sortBy != "" {
-		if err := misc.SortRecords(found, sortBy); err != nil {
-			return nil, err
-		}
+	if showsComments() {
+		found = pdns.JoinComments(found)
 	}
-
 	return found, nil
 }
 
-func watchMode(client *pdns.PDNSAPI, args []string, objectType string) {
-	interval := viper.GetInt("watch-interval")
-	if interval < 1 {
-		interval = 5
+// showsComments reports whether the selected output shows the comments of
+// records, so comment objects can be joined into them. Only table and JSON
+// output, also grouped, print comments, the other outputs and the
+// statistics keep the comments as rows of their own.
+func showsComments() bool {
+	if viper.GetBool("stats") {
+		return false
 	}
+	output := viper.GetString("output")
+	return output == "table" || output == "json"
+}
 
-	clearScreen := viper.GetBool("watch-clear")
-	watchChanges := viper.GetBool("watch-changes")
-	var previous []pdns.PDNSSearchResponseItem
-	ticker := time.NewTicker(time.Duration(interval) * time.Second)
-	defer ticker.Stop()
-
-	ctx := context.Background()
-
-	fetchAndDisplay := func() ([]pdns.PDNSSearchResponseItem, bool) {
-		found, err := fetchAndProcessRecords(ctx, client, args, objectType)
-		if err != nil {
-			log.Error(err)
-			return previous, false
+// addDetails adds comments and disabled flags with --details and applies
+// the disabled filters.
+func addDetails(ctx context.Context, client *pdns.PDNSAPI, found []pdns.PDNSSearchResponseItem) ([]pdns.PDNSSearchResponseItem, error) {
+	if viper.GetBool("details") {
+		if err := pdns.AddDetails(ctx, client, found); err != nil {
+			return nil, err
 		}
-
-		changed := !misc.RecordsEqual(previous, found)
-		return found, changed
 	}
-
-	// Initial fetch
-	found, _ := fetchAndDisplay()
-	if clearScreen {
-		fmt.Print("\033[2J\033[H")
+	if viper.GetBool("only-disabled") {
+		found = pdns.FilterRecordsOnDisabled(found, true)
+	} else if viper.GetBool("hide-disabled") {
+		found = pdns.FilterRecordsOnDisabled(found, false)
 	}
-	fmt.Printf("=== %s ===\n", time.Now().Format("15:04:05"))
-	outputResults(found)
-	previous = found
-
-	for range ticker.C {
-		found, changed := fetchAndDisplay()
-
-		if watchChanges && !changed {
-			continue
-		}
-
-		status := ""
-		if changed {
-			status = " (CHANGED)"
-		}
+	return found, nil
+}
 
-		if clearScreen {
-			fmt.Print("\033[2J\033[H")
-			fmt.Printf("=== %s%s ===\n", time.Now().Format("15:04:05"), status)
-		} else {
-			fmt.Printf("\n=== %s%s ===\n", time.Now().Format("15:04:05"), status)
-		}
+// fetchRecords searches for args and applies the type filter and sorting.
+func fetchRecords(ctx context.Context, client *pdns.PDNSAPI, args []string, objectType, rType, sortBy string) ([]pdns.PDNSSearchResponseItem, error) {
+	found, err := pdns.GetPDNSRecords(ctx, client, args, objectType)
+	if err != nil {
+		return nil, err
+	}
 
-		if watchChanges && changed {
-			added, removed := misc.DiffRecords(previous, found)
-			misc.OutputDiff(added, removed)
-		} else {
-			outputResults(found)
-		}
+	if rType != "" {
+		found = pdns.FilterRecordsOnType(found, rType)
+	}
 
-		if changed {
-			previous = found
+	if sortBy != "" {
+		if err := misc.SortRecords(found, sortBy); err != nil {
+			return nil, err
 		}
 	}
+
+	return found, nil
 }
 
 func createPDNSClient() *pdns.PDNSAPI {
@@ -174,6 +181,7 @@ func createPDNSClient() *pdns.PDNSAPI {
 	timeout := time.Duration(viper.GetInt("timeout")) * time.Second
 	client.Timeout = timeout
 	client.Client.Timeout = timeout
+	client.Limit = viper.GetInt("limit")
 	return client
 }
 
@@ -223,6 +231,13 @@ func initLogLevel() {
 }
 
 func initConfig() {
+	loadConfig()
+	validateConfigValues()
+}
+
+// loadConfig reads the config file and sets up logging and colors. Commands
+// that don't talk to the API use it instead of initConfig.
+func loadConfig() {
 	// set log level from cmd params
 	initLogLevel()
 
@@ -246,7 +261,10 @@ func initConfig() {
 	// set log level in case config has different values
 	initLogLevel()
 
-	validateConfigValues()
+	misc.ConfigureColor(viper.GetBool("no-color"))
+	if err := misc.ApplyTheme(viper.GetString("theme"), viper.GetStringMapString("colors")); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func validateConfigValues() {
@@ -254,37 +272,79 @@ func validateConfigValues() {
 	if token := viper.GetString("token"); token == "" {
 		log.Fatal("Token needs to be defined")
 	}
-	if viper.GetBool("no-color") {
-		color.NoColor = true
-	}
+}
+
+// addOutputFlags adds the flags controlling how results are printed.
+func addOutputFlags(flags *pflag.FlagSet) {
+	flags.StringP("output", "o", "table", "output (table|csv|raw|json|tree)")
+	flags.String("delimiter", ";", "Delimiter when csv export is used")
+	flags.Bool("no-header", false, "do not show header in output")
+	flags.Bool("no-highlight", false, "do not highlight matched search terms in table output")
+	flags.StringP("sort-by", "s", "", "sort results by comma separated fields, prefix with - for descending (name|zone|ttl|type|content)")
+	flags.StringP("group-by", "g", "", "group results (rrset|name|zone)")
+}
+
+// addSearchFlags adds the flags selecting what is searched for.
+func addSearchFlags(flags *pflag.FlagSet) {
+	flags.Bool("zone", false, "search only for zones")
+	flags.Bool("record", false, "search only for records")
+	flags.Bool("comment", false, "search only for comments")
+	flags.StringP("type", "t", "", "filter type of record (A, AAAA, TXT ....)")
+	flags.Int("limit", 0, "stop after N results, applied before the type filter (0 = no limit)")
+}
+
+// addDetailFlags adds the flags of addDetails to cmd.
+func addDetailFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("details", false, "add comments and disabled flags from the zones of the results")
+	cmd.Flags().Bool("only-disabled", false, "show only disabled records")
+	cmd.Flags().Bool("hide-disabled", false, "hide disabled records")
+	cmd.MarkFlagsMutuallyExclusive("only-disabled", "hide-disabled")
+}
+
+// addWatchFlags adds the flags of watch mode.
+func addWatchFlags(flags *pflag.FlagSet) {
+	flags.Int("watch-interval", 5, "interval in seconds for watch mode")
+	flags.Bool("watch-clear", false, "clear screen on each watch update (default: continuous print)")
+	flags.Bool("watch-changes", false, "only show diff when changes are detected")
+	flags.Bool("until-found", false, fmt.Sprintf("end watch mode with exit code %d as soon as records are found", watch.ExitFound))
+	flags.Bool("until-gone", false, fmt.Sprintf("end watch mode with exit code %d as soon as no records are found", watch.ExitGone))
+	flags.Bool("until-changed", false, fmt.Sprintf("end watch mode with exit code %d on the first change", watch.ExitChanged))
+	flags.Duration("watch-timeout", 0, fmt.Sprintf("end watch mode after this duration, with exit code %d if an until condition was not met", watch.ExitTimeout))
+	flags.String("state-file", "", "file to keep the last seen results of watch mode across restarts")
+	flags.String("log", "", "file to append every change as JSON event to")
+	flags.Int("log-max-size", 100, "size in MB after which the event log is rotated")
+	flags.Int("log-max-files", 5, "number of rotated event logs to keep")
+	flags.String("on-change-exec", "", "command to run on changes in watch mode, receives the changes as JSON on stdin")
+	flags.String("on-change-webhook", "", "URL to post changes to in watch mode")
+	flags.String("webhook-format", "generic", "payload of the webhook (generic|slack|mattermost|teams)")
+	flags.Int("hook-retries", 3, "number of retries for failed change hooks")
 }
 
 func init() {
-	rootCmd.Flags().BoolP("debug", "d", false, "enable debug logging")
-	rootCmd.Flags().BoolP("verbose", "v", false, "enable verbose logging")
-	rootCmd.Flags().StringP("config", "c", "", "path to a config file")
-	rootCmd.Flags().String("token", "", "PowerDNS Token")
-	rootCmd.Flags().StringP("url", "u", "", "PowerDNS API URL")
-	rootCmd.Flags().StringP("output", "o", "table", "output (table|csv|raw|json)")
-	rootCmd.Flags().String("delimiter", ";", "Delimiter when csv export is used")
-	rootCmd.Flags().Bool("no-header", false, "do not show header in output")
-	rootCmd.Flags().Bool("no-color", false, "disable colored output")
-	rootCmd.Flags().Bool("zone", false, "search only for zones")
-	rootCmd.Flags().Bool("record", false, "search only for records")
-	rootCmd.Flags().Bool("comment", false, "search only for comments")
-	rootCmd.Flags().StringP("type", "t", "", "filter type of record (A, AAAA, TXT ....)")
-	rootCmd.Flags().IntP("timeout", "", 10, "timeout in seconds for API requests")
+	// the completion is shown with --show-completion, the generated
+	// completion command would only add another word that needs
+	// "pdnsgrep -- completion" to be searched for
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+
+	rootCmd.PersistentFlags().BoolP("debug", "d", false, "enable debug logging")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "enable verbose logging")
+	rootCmd.PersistentFlags().StringP("config", "c", "", "path to a config file")
+	rootCmd.PersistentFlags().String("token", "", "PowerDNS Token")
+	rootCmd.PersistentFlags().StringP("url", "u", "", "PowerDNS API URL")
+	rootCmd.PersistentFlags().IntP("timeout", "", 10, "timeout in seconds for API requests")
+	rootCmd.PersistentFlags().Bool("no-color", false, "disable colored output")
+	rootCmd.PersistentFlags().String("theme", misc.DefaultTheme, "color theme (dark|light|mono)")
+
+	addOutputFlags(rootCmd.Flags())
+	addSearchFlags(rootCmd.Flags())
 	rootCmd.Flags().String("show-completion", "", "show completion (bash, zsh, fish, powershell)")
-	rootCmd.Flags().StringP("sort-by", "s", "", "sort results by field (name|zone|ttl|type)")
 	rootCmd.Flags().Bool("stats", false, "show statistics instead of full output")
+	rootCmd.Flags().String("stats-format", "table", "format of the statistics (table|csv|json)")
+	rootCmd.Flags().Int("top", 0, "limit statistics to the top N zones, names and contents")
+	addDetailFlags(rootCmd)
 	rootCmd.Flags().BoolP("watch", "w", false, "continuously poll and show changes")
-	rootCmd.Flags().Int("watch-interval", 5, "interval in seconds for watch mode")
-	rootCmd.Flags().Bool("watch-clear", false, "clear screen on each watch update (default: continuous print)")
-	rootCmd.Flags().Bool("watch-changes", false, "only show diff when changes are detected")
+	addWatchFlags(rootCmd.Flags())
 
 	viper.AutomaticEnv()
 	viper.SetEnvPrefix("PDNSGREP")
-
-	// bind all cobra flags to viper
-	viper.BindPFlags(rootCmd.Flags())
 }
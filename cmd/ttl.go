@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/edit"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ttlCmd = &cobra.Command{
+	Use:   "ttl TTL SEARCH...",
+	Short: "Change the TTL of all RRsets found by the search",
+	Long: `Ttl sets the TTL of every RRset with a record found by the search and keeps
+its records. With --save the previous TTLs are written to a file, which
+--restore puts back.`,
+	Example: `pdnsgrep ttl 300 "*.example.com." --save ttls.json
+pdnsgrep ttl --restore ttls.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		restoreFile := viper.GetString("restore")
+		saveFile := viper.GetString("save")
+		switch {
+		case restoreFile != "" && len(args) > 0:
+			log.Fatal("a TTL and search terms can't be combined with --restore")
+		case restoreFile != "" && saveFile != "":
+			log.Fatal("--save can't be combined with --restore")
+		case restoreFile == "" && len(args) < 2:
+			log.Fatal("a TTL and at least one search term are required")
+		}
+
+		initConfig()
+		client := createPDNSClient()
+
+		var changes []pdns.RRSetChange
+		var err error
+		if restoreFile != "" {
+			changes, err = restoreTTLChanges(client, restoreFile)
+		} else {
+			ttl, convErr := strconv.Atoi(args[0])
+			if convErr != nil || ttl < 0 {
+				log.Fatalf("invalid TTL %s", args[0])
+			}
+			changes, err = ttlChanges(client, args[1:], ttl)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var save func() error
+		if saveFile != "" {
+			save = func() error {
+				if err := saveTTLs(saveFile, changes); err != nil {
+					return err
+				}
+				log.Infof("saved %d previous TTLs to %s", len(changes), saveFile)
+				return nil
+			}
+		}
+		if err := applyChanges(client, changes, applyOptions{beforePatch: save}); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// withTTL returns the change setting the TTL of rrset, or false if it
+// already has the TTL. The comments are kept by the patch.
+func withTTL(zone string, rrset pdns.RRSet, ttl int) (pdns.RRSetChange, bool) {
+	if rrset.TTL == ttl {
+		return pdns.RRSetChange{}, false
+	}
+	after := rrset
+	after.TTL = ttl
+	after.Comments = nil
+	return pdns.RRSetChange{Zone: zone, Before: &rrset, After: &after}, true
+}
+
+// ttlChanges returns the changes setting the TTL of the RRsets found by the
+// search for terms.
+func ttlChanges(client *pdns.PDNSAPI, terms []string, ttl int) ([]pdns.RRSetChange, error) {
+	found, err := searchRRSets(client, terms, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []pdns.RRSetChange
+	for _, f := range found {
+		if change, ok := withTTL(f.zone.Name, f.rrset, ttl); ok {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// restoreTTLChanges returns the changes setting the TTLs saved in path
+// again. RRsets deleted since are skipped with a warning.
+func restoreTTLChanges(client *pdns.PDNSAPI, path string) ([]pdns.RRSetChange, error) {
+	saved, err := edit.LoadTTLs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make(map[string]*pdns.Zone)
+	var changes []pdns.RRSetChange
+	for _, s := range saved.RRSets {
+		zone, ok := zones[s.Zone]
+		if !ok {
+			zone, err = client.GetZone(s.Zone)
+			if err != nil {
+				return nil, err
+			}
+			zones[s.Zone] = zone
+		}
+
+		rrset, ok := zone.FindRRSet(s.Name, s.Type)
+		if !ok {
+			log.Warnf("skipping %s %s: RRset no longer exists in zone %s", s.Name, s.Type, s.Zone)
+			continue
+		}
+		if change, ok := withTTL(zone.Name, rrset, s.TTL); ok {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// saveTTLs writes the TTLs of the RRsets before the changes to path.
+func saveTTLs(path string, changes []pdns.RRSetChange) error {
+	ttls := edit.TTLFile{SavedAt: time.Now()}
+	for _, c := range changes {
+		ttls.RRSets = append(ttls.RRSets, edit.SavedTTL{Zone: c.Zone, Name: c.Before.Name, Type: c.Before.Type, TTL: c.Before.TTL})
+	}
+	return edit.SaveTTLs(path, ttls)
+}
+
+func init() {
+	addEditFlags(ttlCmd.Flags())
+	ttlCmd.Flags().Lookup("zone").Usage = "only change records of this zone"
+	ttlCmd.Flags().StringP("type", "t", "", "only change RRsets of this type (A, AAAA, TXT ....)")
+	ttlCmd.Flags().String("save", "", "save the previous TTLs to this file for --restore")
+	ttlCmd.Flags().String("restore", "", "restore the TTLs saved with --save in this file")
+	rootCmd.AddCommand(ttlCmd)
+}
@@ -0,0 +1,446 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/akquinet/pdnsgrep/watch"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch SEARCH [SEARCH...]",
+	Short: "Continuously poll a search and show changes",
+	Example: `pdnsgrep watch --watch-changes "*firewall*"
+pdnsgrep watch --log events.ndjson "*"
+pdnsgrep watch --watch-config queries.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configFile := viper.GetString("watch-config")
+		if configFile == "" && len(args) == 0 {
+			log.Fatal("at least one search term or --watch-config is required")
+		}
+		if configFile != "" && len(args) > 0 {
+			log.Fatal("search terms can't be combined with --watch-config")
+		}
+
+		client, objectType := prepareSearch(args)
+		if configFile != "" {
+			os.Exit(watchDaemon(client, configFile))
+		}
+		os.Exit(watchMode(client, args, objectType))
+	},
+}
+
+func init() {
+	addOutputFlags(watchCmd.Flags())
+	addSearchFlags(watchCmd.Flags())
+	addDetailFlags(watchCmd)
+	addWatchFlags(watchCmd.Flags())
+	watchCmd.Flags().String("watch-config", "", "file with named queries to watch at the same time")
+	rootCmd.AddCommand(watchCmd)
+}
+
+// maxSkippedPolls forces a search after this many polls were skipped
+// because of unchanged zone serials, not every change bumps a serial.
+const maxSkippedPolls = 10
+
+// watcher polls a single search and reports its changes.
+type watcher struct {
+	client     *pdns.PDNSAPI
+	terms      []string
+	objectType string
+	rType      string
+	sortBy     string
+	interval   time.Duration
+
+	hooks      watch.Hooks
+	hookQueue  *watch.HookQueue
+	eventLog   *watch.EventLog
+	display    watchDisplay
+	conditions watch.Conditions
+	stateFile  string
+
+	// limit is shared by watchers of the same process and bounds the
+	// number of searches running at the same time
+	limit chan struct{}
+
+	// serials of the zones at the last search, used to skip searches if
+	// the API doesn't support ETags
+	serials map[string]int
+	skipped int
+}
+
+// newWatcher sets up a watcher for terms from the watch flags.
+func newWatcher(client *pdns.PDNSAPI, terms []string, objectType string) *watcher {
+	interval := viper.GetInt("watch-interval")
+	if interval < 1 {
+		interval = 5
+	}
+	if err := watch.ValidateWebhookFormat(viper.GetString("webhook-format")); err != nil {
+		log.Fatal(err)
+	}
+
+	w := &watcher{
+		client:     client,
+		terms:      terms,
+		objectType: objectType,
+		rType:      viper.GetString("type"),
+		sortBy:     viper.GetString("sort-by"),
+		interval:   time.Duration(interval) * time.Second,
+		hooks: watch.Hooks{
+			Exec:          viper.GetString("on-change-exec"),
+			Webhook:       viper.GetString("on-change-webhook"),
+			WebhookFormat: viper.GetString("webhook-format"),
+			Retries:       viper.GetInt("hook-retries"),
+		},
+		display: watchDisplay{
+			clearScreen:  viper.GetBool("watch-clear"),
+			watchChanges: viper.GetBool("watch-changes"),
+			jsonOutput:   viper.GetString("output") == "json",
+			mu:           &sync.Mutex{},
+		},
+		conditions: watch.Conditions{
+			Found:   viper.GetBool("until-found"),
+			Gone:    viper.GetBool("until-gone"),
+			Changed: viper.GetBool("until-changed"),
+		},
+		eventLog:  newEventLog(),
+		stateFile: viper.GetString("state-file"),
+		limit:     make(chan struct{}, 1),
+	}
+	return w
+}
+
+// newEventLog returns the event log given with --log, nil without one.
+func newEventLog() *watch.EventLog {
+	logFile := viper.GetString("log")
+	if logFile == "" {
+		return nil
+	}
+	return &watch.EventLog{
+		Path:     logFile,
+		MaxSize:  int64(viper.GetInt("log-max-size")) * 1024 * 1024,
+		MaxFiles: viper.GetInt("log-max-files"),
+	}
+}
+
+// watchContext returns the context of watch mode, which ends after the
+// watch timeout if one is set.
+func watchContext() (context.Context, context.CancelFunc) {
+	if d := viper.GetDuration("watch-timeout"); d > 0 {
+		return context.WithTimeout(context.Background(), d)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// watchMode polls the search until an exit condition or the timeout is
+// reached and returns the exit code. Without any of them it never returns.
+func watchMode(client *pdns.PDNSAPI, args []string, objectType string) int {
+	ctx, cancel := watchContext()
+	defer cancel()
+	client.ConditionalRequests = true
+	return newWatcher(client, args, objectType).run(ctx)
+}
+
+// watchDaemon polls all queries of the watch config on their own interval
+// until the watch timeout is reached. Without a timeout it never returns.
+func watchDaemon(client *pdns.PDNSAPI, configFile string) int {
+	if viper.GetBool("until-found") || viper.GetBool("until-gone") || viper.GetBool("until-changed") {
+		log.Fatal("until conditions can't be used with --watch-config")
+	}
+	if viper.GetString("state-file") != "" {
+		log.Fatal("--state-file can't be used with --watch-config")
+	}
+
+	defaultInterval := time.Duration(max(viper.GetInt("watch-interval"), 1)) * time.Second
+	config, err := watch.LoadConfig(configFile, defaultInterval)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := watchContext()
+	defer cancel()
+
+	client.ConditionalRequests = true
+	limit := make(chan struct{}, config.Concurrency)
+	// one event log for all queries, so only one of them rotates the file
+	eventLog := newEventLog()
+	mu := &sync.Mutex{}
+	var wg sync.WaitGroup
+	for _, q := range config.Queries {
+		w := newWatcher(client, q.Terms, q.ObjectType)
+		w.rType = q.Type
+		w.sortBy = q.SortBy
+		w.interval = q.Interval
+		w.limit = limit
+		w.eventLog = eventLog
+		w.display.label = q.Name
+		w.display.clearScreen = false
+		w.display.mu = mu
+		if q.OnChangeExec != "" {
+			w.hooks.Exec = q.OnChangeExec
+		}
+		if q.OnChangeWebhook != "" {
+			w.hooks.Webhook = q.OnChangeWebhook
+		}
+		if q.WebhookFormat != "" {
+			w.hooks.WebhookFormat = q.WebhookFormat
+		}
+
+		log.Infof("watching %s every %s", q.Name, q.Interval)
+		wg.Go(func() { w.run(ctx) })
+	}
+	wg.Wait()
+	return 0
+}
+
+// fetch runs the search once a slot of the shared limit is free. The search
+// is skipped and reported as such if no zone serial changed since the last
+// search.
+func (w *watcher) fetch(ctx context.Context) ([]pdns.PDNSSearchResponseItem, bool, error) {
+	select {
+	case w.limit <- struct{}{}:
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+	defer func() { <-w.limit }()
+
+	start := time.Now()
+	defer func() {
+		if elapsed := time.Since(start); elapsed > w.interval {
+			log.Warnf("%ssearch took %s, longer than the interval of %s", w.display.prefix(), elapsed.Round(time.Millisecond), w.interval)
+		}
+	}()
+
+	serials, unchanged := w.zoneSerials(ctx)
+	if unchanged && w.skipped < maxSkippedPolls {
+		w.skipped++
+		log.Debugf("%szone serials unchanged, skipping search", w.display.prefix())
+		return nil, true, nil
+	}
+
+	found, err := fetchRecords(ctx, w.client, w.terms, w.objectType, w.rType, w.sortBy)
+	if err != nil {
+		return nil, false, err
+	}
+	if found, err = addDetails(ctx, w.client, found); err != nil {
+		return nil, false, err
+	}
+	w.serials = serials
+	w.skipped = 0
+	return found, false, nil
+}
+
+// zoneSerials returns the serials of all zones and whether they match the
+// serials at the last search. Serials are only checked if the API doesn't
+// answer searches with ETags, which already avoid transferring unchanged
+// results.
+func (w *watcher) zoneSerials(ctx context.Context) (map[string]int, bool) {
+	if w.client.SupportsETags() {
+		return nil, false
+	}
+	zones, err := w.client.ListZones(ctx)
+	if err != nil {
+		log.Debugf("listing zones for serial check: %v", err)
+		return nil, false
+	}
+	serials := make(map[string]int, len(zones))
+	for _, z := range zones {
+		serials[z.Name] = z.Serial
+	}
+	return serials, w.serials != nil && maps.Equal(w.serials, serials)
+}
+
+// restoreState returns the results saved by a previous run, if any.
+func (w *watcher) restoreState() ([]pdns.PDNSSearchResponseItem, bool) {
+	if w.stateFile == "" {
+		return nil, false
+	}
+	state, err := watch.LoadState(w.stateFile)
+	switch {
+	case err != nil:
+		log.Error(err)
+	case state == nil:
+		log.Infof("no state in %s yet, starting with a new baseline", w.stateFile)
+	case !state.Matches(w.terms, w.objectType, w.rType):
+		log.Warnf("state in %s was saved for another search, starting with a new baseline", w.stateFile)
+	default:
+		log.Infof("restored %d records saved at %s", len(state.Records), state.SavedAt.Format(time.RFC3339))
+		return state.Records, true
+	}
+	return nil, false
+}
+
+func (w *watcher) saveState(records []pdns.PDNSSearchResponseItem) {
+	if w.stateFile == "" {
+		return
+	}
+	state := watch.State{Query: w.terms, ObjectType: w.objectType, Type: w.rType, SavedAt: time.Now(), Records: records}
+	if err := watch.SaveState(w.stateFile, state); err != nil {
+		log.Errorf("saving state: %v", err)
+	}
+}
+
+// notify writes the changes to the event log and queues them for the hooks.
+// The changes are always the diff of two successful polls.
+func (w *watcher) notify(changes []misc.RecordChange) {
+	if len(changes) == 0 {
+		return
+	}
+	now := time.Now()
+	if w.eventLog != nil {
+		if err := w.eventLog.Write(now, w.display.label, changes); err != nil {
+			log.Errorf("writing event log: %v", err)
+		}
+	}
+	if w.hookQueue != nil {
+		event := watch.Event{Time: now, Name: w.display.label, Query: w.terms, Changes: changes}
+		if !w.hookQueue.Push(event) {
+			log.Warnf("%shooks are falling behind, dropping %d changes", w.display.prefix(), len(changes))
+		}
+	}
+}
+
+// run polls the search until an exit condition is met or ctx ends and
+// returns the exit code.
+func (w *watcher) run(ctx context.Context) int {
+	schedule := watch.Schedule{Interval: w.interval, Jitter: watch.DefaultJitter}
+	failures := 0
+
+	if w.hooks.Enabled() {
+		// the queued changes are still delivered when the watch ends
+		w.hookQueue = watch.NewHookQueue(w.hooks, 0)
+		defer w.hookQueue.Close()
+	}
+
+	// previous is the baseline the next poll is compared with, restored or
+	// taken from the first successful search. No changes are reported
+	// without one, a failed first search would report all records as added.
+	previous, baselined := w.restoreState()
+	printed := false
+	show := func(found []pdns.PDNSSearchResponseItem, changes []misc.RecordChange) {
+		w.display.update(found, changes, !printed)
+		printed = true
+	}
+
+	// a timer instead of a ticker, so slow searches delay the next poll
+	// instead of piling up ticks. The first poll starts right away.
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info(w.display.prefix() + "watch timeout reached")
+			if w.conditions.Enabled() {
+				return watch.ExitTimeout
+			}
+			return 0
+		case <-timer.C:
+		}
+
+		found, skipped, err := w.fetch(ctx)
+		switch {
+		case err != nil:
+			failures++
+			if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+				log.Error(w.display.prefix() + err.Error())
+			}
+			if baselined && !w.display.watchChanges {
+				show(previous, nil)
+			}
+		case skipped:
+			failures = 0
+			if !w.display.watchChanges {
+				show(previous, nil)
+			}
+		case !baselined:
+			failures = 0
+			baselined = true
+			previous = found
+			w.saveState(previous)
+			show(found, nil)
+			if met, code := w.conditions.Check(found, false); met {
+				return code
+			}
+		default:
+			failures = 0
+			changes := misc.DiffRRsets(previous, found)
+			changed := len(changes) > 0
+			w.notify(changes)
+			if changed || !w.display.watchChanges || !printed {
+				show(found, changes)
+			}
+			if changed {
+				previous = found
+				w.saveState(previous)
+			}
+			if met, code := w.conditions.Check(found, changed); met {
+				return code
+			}
+		}
+		timer.Reset(schedule.Next(failures))
+	}
+}
+
+// watchDisplay prints the poll results of watch mode. Displays sharing mu
+// don't interleave their output.
+type watchDisplay struct {
+	label        string
+	clearScreen  bool
+	watchChanges bool
+	jsonOutput   bool
+	mu           *sync.Mutex
+}
+
+// prefix returns the label of the query for headers and log messages.
+func (d watchDisplay) prefix() string {
+	if d.label == "" {
+		return ""
+	}
+	return "[" + d.label + "] "
+}
+
+// update prints a poll result. In changes mode only the diff is shown, as a
+// JSON line if JSON output is selected.
+func (d watchDisplay) update(found []pdns.PDNSSearchResponseItem, changes []misc.RecordChange, first bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	changed := len(changes) > 0
+	showDiff := d.watchChanges && changed
+
+	if showDiff && d.jsonOutput {
+		if err := misc.OutputDiffJSON(time.Now(), d.label, changes); err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
+	if d.clearScreen {
+		fmt.Print("\033[2J\033[H")
+	} else if !first || d.label != "" {
+		fmt.Println()
+	}
+
+	status := ""
+	if changed {
+		status = " (CHANGED)"
+	}
+	fmt.Printf("=== %s%s%s ===\n", d.prefix(), time.Now().Format("15:04:05"), status)
+
+	if showDiff {
+		misc.OutputDiff(changes)
+	} else {
+		outputResults(found)
+	}
+}
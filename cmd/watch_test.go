@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/akquinet/pdnsgrep/watch"
+	"github.com/spf13/viper"
+)
+
+func TestWatcherFailedFirstSearch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a POSIX shell")
+	}
+
+	var searches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/search-data") {
+			w.Write([]byte(`[]`))
+			return
+		}
+		if searches.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`[{"name": "fw.example.com.", "type": "A", "content": "10.0.0.1", "object_type": "record", "zone": "example.com.", "ttl": 300}]`))
+	}))
+	defer server.Close()
+
+	viper.Set("output", "table")
+	t.Cleanup(viper.Reset)
+
+	logPath := filepath.Join(t.TempDir(), "events.ndjson")
+	hookPath := filepath.Join(t.TempDir(), "hook.json")
+	w := &watcher{
+		client:     pdns.NewPDNSAPI(server.URL, "secret"),
+		terms:      []string{"fw"},
+		objectType: "record",
+		interval:   10 * time.Millisecond,
+		hooks:      watch.Hooks{Exec: "cat > " + hookPath},
+		eventLog:   &watch.EventLog{Path: logPath},
+		display:    watchDisplay{watchChanges: true, mu: &sync.Mutex{}},
+		conditions: watch.Conditions{Changed: true},
+		limit:      make(chan struct{}, 1),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if code := w.run(ctx); code != watch.ExitTimeout {
+		t.Errorf("expected exit code %d, got %d", watch.ExitTimeout, code)
+	}
+	if n := searches.Load(); n < 3 {
+		t.Fatalf("expected at least 3 searches, got %d", n)
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("expected no events to be logged, got %v", err)
+	}
+	if _, err := os.Stat(hookPath); !os.IsNotExist(err) {
+		t.Errorf("expected the hook not to run, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var zoneCmd = &cobra.Command{
+	Use:   "zone",
+	Short: "Work with whole zones",
+}
+
+var zoneExportCmd = &cobra.Command{
+	Use:   "export ZONE [ZONE...]",
+	Short: "Export all records of zones",
+	Example: `pdnsgrep zone export example.com. --output zone
+pdnsgrep zone export "*.internal." --output json`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		client := createPDNSClient()
+
+		names, err := resolveZones(client, args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(names) == 0 {
+			fmt.Println("Nothing found")
+			os.Exit(0)
+		}
+
+		var zones []pdns.Zone
+		for _, name := range names {
+			zone, err := client.GetZone(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			zones = append(zones, *zone)
+		}
+
+		switch viper.GetString("output") {
+		case "zone":
+			for i, zone := range zones {
+				if i > 0 {
+					fmt.Println()
+				}
+				misc.WriteZoneFile(os.Stdout, zone)
+			}
+		default:
+			var records []pdns.PDNSSearchResponseItem
+			for _, zone := range zones {
+				records = append(records, zone.Records()...)
+			}
+			sortBy := viper.GetString("sort-by")
+			if sortBy == "" {
+				sortBy = "zone,name,type"
+			}
+			if err := misc.SortRecords(records, sortBy); err != nil {
+				log.Fatal(err)
+			}
+			outputResults(records)
+		}
+	},
+}
+
+// resolveZones returns the zone names for args. Arguments with wildcards
+// are matched against all zones of the server.
+func resolveZones(client *pdns.PDNSAPI, args []string) ([]string, error) {
+	var names []string
+	var all []pdns.Zone
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			if name := pdns.CanonicalName(arg); !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+			continue
+		}
+
+		if all == nil {
+			var err error
+			if all, err = client.ListZones(context.Background()); err != nil {
+				return nil, err
+			}
+		}
+		matched, err := pdns.MatchZones(all, arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, z := range matched {
+			if !seen[z.Name] {
+				seen[z.Name] = true
+				names = append(names, z.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func init() {
+	addOutputFlags(zoneExportCmd.Flags())
+	zoneExportCmd.Flags().Lookup("output").Usage = "output (table|csv|raw|json|tree|zone)"
+	zoneCmd.AddCommand(zoneExportCmd)
+	rootCmd.AddCommand(zoneCmd)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var zonesCmd = &cobra.Command{
+	Use:   "zones [PATTERN]",
+	Short: "List zones with their kind, serial and DNSSEC state",
+	Example: `pdnsgrep zones
+pdnsgrep zones "*.example.com." --kind Slave --sort-by -serial`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		initConfig()
+
+		zones, err := createPDNSClient().ListZones(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(args) > 0 {
+			zones, err = pdns.MatchZones(zones, args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		if kind := viper.GetString("kind"); kind != "" {
+			zones = pdns.FilterZonesOnKind(zones, kind)
+		}
+		if cmd.Flags().Changed("dnssec") {
+			zones = pdns.FilterZonesOnDNSSec(zones, viper.GetBool("dnssec"))
+		}
+
+		if len(zones) == 0 {
+			fmt.Println("Nothing found")
+			os.Exit(0)
+		}
+
+		if err := misc.SortZones(zones, viper.GetString("sort-by")); err != nil {
+			log.Fatal(err)
+		}
+		if err := misc.OutputZones(zones, viper.GetString("output"), viper.GetString("delimiter")); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	zonesCmd.Flags().StringP("output", "o", "table", "output (table|csv|raw|json)")
+	zonesCmd.Flags().String("delimiter", ";", "Delimiter when csv export is used")
+	zonesCmd.Flags().Bool("no-header", false, "do not show header in output")
+	zonesCmd.Flags().StringP("sort-by", "s", "name", "sort zones by comma separated fields, prefix with - for descending (name|kind|serial|account|dnssec)")
+	zonesCmd.Flags().String("kind", "", "filter kind of zone (Native, Master, Slave)")
+	zonesCmd.Flags().Bool("dnssec", false, "show only zones with (--dnssec) or without (--dnssec=false) DNSSEC")
+	rootCmd.AddCommand(zonesCmd)
+}
@@ -0,0 +1,178 @@
+package edit
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"go.yaml.in/yaml/v3"
+)
+
+// ParseRecords parses a YAML or JSON list of records in the schema of the
+// JSON output. The YAML is converted to JSON first, so both use the same
+// field names.
+func ParseRecords(data []byte) ([]pdns.PDNSSearchResponseItem, error) {
+	var list []any
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("decoding records: %w", err)
+	}
+	converted, err := json.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("decoding records: %w", err)
+	}
+
+	var records []pdns.PDNSSearchResponseItem
+	if err := json.Unmarshal(converted, &records); err != nil {
+		return nil, fmt.Errorf("decoding records: %w", err)
+	}
+	return records, nil
+}
+
+// DesiredRRSets groups the records into RRsets per zone. Records of an RRset
+// must have the same TTL. Comments are only set if a record has some, see
+// Plan for how RRsets without comments are applied. Objects other than records are
+// ignored.
+func DesiredRRSets(records []pdns.PDNSSearchResponseItem) (map[string][]pdns.RRSet, error) {
+	type key struct{ zone, name, rType string }
+	var keys []key
+	rrsets := make(map[key]*pdns.RRSet)
+
+	for i, r := range records {
+		if r.ObjectType != "" && r.ObjectType != "record" {
+			continue
+		}
+		if r.Zone == "" || r.Name == "" || r.Type == "" {
+			return nil, fmt.Errorf("record %d: zone, name and type are required", i+1)
+		}
+		k := key{pdns.CanonicalName(r.Zone), pdns.CanonicalName(r.Name), strings.ToUpper(r.Type)}
+		rrset, ok := rrsets[k]
+		if !ok {
+			rrset = &pdns.RRSet{Name: k.name, Type: k.rType, TTL: r.Ttl}
+			rrsets[k] = rrset
+			keys = append(keys, k)
+		} else if rrset.TTL != r.Ttl {
+			return nil, fmt.Errorf("record %d: TTL %d differs from TTL %d of the %s %s RRset", i+1, r.Ttl, rrset.TTL, k.name, k.rType)
+		}
+		rrset.Records = append(rrset.Records, pdns.Record{Content: r.Content, Disabled: r.Disabled})
+		if len(r.Comments) > 0 {
+			rrset.Comments = r.Comments
+		}
+	}
+
+	byZone := make(map[string][]pdns.RRSet)
+	for _, k := range keys {
+		byZone[k.zone] = append(byZone[k.zone], *rrsets[k])
+	}
+	return byZone, nil
+}
+
+// Plan returns the changes turning zone into the desired RRsets. Without
+// prune the records and comments of a current RRset that aren't desired are
+// kept, as a search export only holds the matching records, and RRsets
+// without desired comments keep all of theirs. With prune they are removed,
+// a desired RRset without comments loses all of its comments, and the RRsets
+// at the names of desired RRsets that aren't desired themselves are deleted. SOA RRsets are managed by PowerDNS and
+// never changed. A desired comment with the content of a current one keeps
+// its account and time. The changes are ordered by name and type.
+func Plan(zone *pdns.Zone, desired []pdns.RRSet, prune bool) []pdns.RRSetChange {
+	var changes []pdns.RRSetChange
+	names := make(map[string]bool)
+	wanted := make(map[string]bool)
+	for _, rrset := range desired {
+		names[strings.ToLower(rrset.Name)] = true
+		wanted[rrsetID(rrset)] = true
+		if rrset.Type == "SOA" {
+			continue
+		}
+
+		current, ok := zone.FindRRSet(rrset.Name, rrset.Type)
+		if !ok {
+			changes = append(changes, pdns.RRSetChange{Zone: zone.Name, After: &rrset})
+			continue
+		}
+		if !prune {
+			rrset.Records = append(slices.Clone(rrset.Records), missingRecords(rrset.Records, current.Records)...)
+		}
+		switch {
+		case rrset.Comments != nil:
+			rrset.Comments = currentComments(rrset.Comments, current.Comments, !prune)
+		case prune:
+			rrset.Comments = []pdns.Comment{}
+		}
+		kept := rrset
+		if kept.Comments == nil {
+			kept.Comments = current.Comments
+		}
+		if !current.Equal(kept) {
+			changes = append(changes, pdns.RRSetChange{Zone: zone.Name, Before: &current, After: &rrset})
+		}
+	}
+
+	if prune {
+		for _, current := range zone.RRSets {
+			if current.Type == "SOA" || !names[strings.ToLower(current.Name)] || wanted[rrsetID(current)] {
+				continue
+			}
+			changes = append(changes, pdns.RRSetChange{Zone: zone.Name, Before: &current})
+		}
+	}
+
+	slices.SortStableFunc(changes, func(a, b pdns.RRSetChange) int {
+		ra, rb := changedRRSet(a), changedRRSet(b)
+		return cmp.Or(misc.CompareDNSNames(ra.Name, rb.Name), strings.Compare(ra.Type, rb.Type))
+	})
+	return changes
+}
+
+// missingRecords returns the current records without a desired record of
+// the same content.
+func missingRecords(desired, current []pdns.Record) []pdns.Record {
+	var missing []pdns.Record
+	for _, cur := range current {
+		if !slices.ContainsFunc(desired, func(r pdns.Record) bool { return r.Content == cur.Content }) {
+			missing = append(missing, cur)
+		}
+	}
+	return missing
+}
+
+// currentComments returns the comments with the current comment of the
+// same content in place of each, as the JSON output of a search only holds
+// the content of comments joined into records. With keep the current
+// comments without a desired one are appended.
+func currentComments(comments, current []pdns.Comment, keep bool) []pdns.Comment {
+	result := slices.Clone(comments)
+	used := make([]bool, len(current))
+	for i, c := range result {
+		for j, cur := range current {
+			if !used[j] && cur.Content == c.Content {
+				result[i], used[j] = cur, true
+				break
+			}
+		}
+	}
+	if keep {
+		for j, cur := range current {
+			if !used[j] {
+				result = append(result, cur)
+			}
+		}
+	}
+	return result
+}
+
+// rrsetID identifies an RRset of a zone, names are compared ignoring case.
+func rrsetID(rrset pdns.RRSet) string {
+	return strings.ToLower(rrset.Name) + " " + strings.ToUpper(rrset.Type)
+}
+
+func changedRRSet(c pdns.RRSetChange) *pdns.RRSet {
+	if c.After != nil {
+		return c.After
+	}
+	return c.Before
+}
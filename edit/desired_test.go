@@ -0,0 +1,229 @@
+package edit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+func TestParseRecords(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"json", `[{"zone": "example.com.", "name": "fw.example.com.", "type": "A", "content": "10.0.0.1", "ttl": 300, "disabled": true}]`},
+		{"yaml", `
+- zone: example.com.
+  name: fw.example.com.
+  type: A
+  content: 10.0.0.1
+  ttl: 300
+  disabled: true
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ParseRecords([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := pdns.PDNSSearchResponseItem{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "10.0.0.1", Ttl: 300, Disabled: true}
+			if len(records) != 1 || records[0].Zone != expected.Zone || records[0].Content != expected.Content || records[0].Ttl != expected.Ttl || !records[0].Disabled {
+				t.Errorf("expected %+v, got %+v", expected, records)
+			}
+		})
+	}
+
+	if _, err := ParseRecords([]byte(`{"zone": "example.com."}`)); err == nil {
+		t.Error("expected error for an object instead of a list, got nil")
+	}
+}
+
+func TestDesiredRRSets(t *testing.T) {
+	records := []pdns.PDNSSearchResponseItem{
+		{Zone: "example.com", Name: "fw.example.com", Type: "a", Content: "10.0.0.1", Ttl: 300, ObjectType: "record"},
+		{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "10.0.0.2", Ttl: 300},
+		{Zone: "example.com.", Name: "example.com.", Type: "ZONE", ObjectType: "zone"},
+	}
+	byZone, err := DesiredRRSets(records)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rrsets := byZone["example.com."]
+	if len(byZone) != 1 || len(rrsets) != 1 || len(rrsets[0].Records) != 2 || rrsets[0].Type != "A" {
+		t.Errorf("expected one A RRset with two records, got %+v", byZone)
+	}
+
+	records[1].Ttl = 60
+	if _, err := DesiredRRSets(records); err == nil {
+		t.Error("expected error for differing TTLs, got nil")
+	}
+	if _, err := DesiredRRSets([]pdns.PDNSSearchResponseItem{{Name: "fw.example.com.", Type: "A"}}); err == nil {
+		t.Error("expected error for missing zone, got nil")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	comments := []pdns.Comment{{Content: "primary firewall"}}
+	zone := &pdns.Zone{Name: "example.com.", RRSets: []pdns.RRSet{
+		{Name: "example.com.", Type: "SOA", TTL: 3600, Records: []pdns.Record{{Content: "ns1.example.com. hostmaster.example.com. 7 10800 3600 604800 3600"}}},
+		{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}}, Comments: comments},
+		{Name: "fw.example.com.", Type: "TXT", TTL: 300, Records: []pdns.Record{{Content: `"unmanaged"`}}},
+		{Name: "mail.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.3"}}},
+		{Name: "www.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.4"}}},
+	}}
+	desired := []pdns.RRSet{
+		{Name: "example.com.", Type: "SOA", TTL: 3600, Records: []pdns.Record{{Content: "ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600"}}},
+		{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.2"}}},
+		{Name: "new.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.5"}}},
+		{Name: "www.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.4"}}},
+	}
+
+	tests := []struct {
+		name     string
+		prune    bool
+		expected []string
+	}{
+		{"without prune", false, []string{"change fw.example.com. A", "add new.example.com. A"}},
+		{"with prune", true, []string{"change fw.example.com. A", "destroy fw.example.com. TXT", "add new.example.com. A"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range Plan(zone, desired, tt.prune) {
+				switch {
+				case c.Before == nil:
+					got = append(got, "add "+c.After.Name+" "+c.After.Type)
+				case c.After == nil:
+					got = append(got, "destroy "+c.Before.Name+" "+c.Before.Type)
+				default:
+					got = append(got, "change "+c.After.Name+" "+c.After.Type)
+				}
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPlanPruneIgnoresCase(t *testing.T) {
+	zone := &pdns.Zone{Name: "example.com.", RRSets: []pdns.RRSet{
+		{Name: "FW.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}}},
+		{Name: "FW.example.com.", Type: "TXT", TTL: 300, Records: []pdns.Record{{Content: `"unmanaged"`}}},
+	}}
+	desired := []pdns.RRSet{
+		{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}}},
+	}
+
+	changes := Plan(zone, desired, true)
+	if len(changes) != 1 || changes[0].After != nil || changes[0].Before.Type != "TXT" {
+		t.Fatalf("expected only the TXT RRset to be destroyed, got %+v", changes)
+	}
+}
+
+func TestPlanKeepsCommentMetadata(t *testing.T) {
+	owner := pdns.Comment{Content: "primary firewall", Account: "alice", ModifiedAt: 1714650000}
+	zone := &pdns.Zone{Name: "example.com.", RRSets: []pdns.RRSet{
+		{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}}, Comments: []pdns.Comment{owner}},
+	}}
+
+	exported := []pdns.RRSet{
+		{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}}, Comments: []pdns.Comment{{Content: "primary firewall"}}},
+	}
+	if changes := Plan(zone, exported, false); len(changes) != 0 {
+		t.Errorf("expected no changes for comments without metadata, got %+v", changes)
+	}
+
+	edited := []pdns.RRSet{
+		{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}},
+			Comments: []pdns.Comment{{Content: "primary firewall"}, {Content: "INC-1234"}}},
+	}
+	changes := Plan(zone, edited, false)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %+v", changes)
+	}
+	if comments := changes[0].After.Comments; len(comments) != 2 || comments[0] != owner || comments[1] != (pdns.Comment{Content: "INC-1234"}) {
+		t.Errorf("expected the current comment to be kept, got %+v", comments)
+	}
+	if edited[0].Comments[0] != (pdns.Comment{Content: "primary firewall"}) {
+		t.Errorf("expected the desired RRsets to be left as they are, got %+v", edited[0].Comments)
+	}
+
+	data, err := json.Marshal(pdns.NewZonePatch(changes))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `{"content":"primary firewall","account":"alice","modified_at":1714650000}`) ||
+		!strings.Contains(string(data), `{"content":"INC-1234","account":""}`) {
+		t.Errorf("unexpected comments in patch %s", data)
+	}
+}
+
+func TestPlanKeepsUnlistedRecords(t *testing.T) {
+	owner := pdns.Comment{Content: "primary firewall", Account: "alice"}
+	zone := &pdns.Zone{Name: "example.com.", RRSets: []pdns.RRSet{
+		{Name: "fw.example.com.", Type: "A", TTL: 300,
+			Records:  []pdns.Record{{Content: "10.0.0.1"}, {Content: "10.0.0.2"}},
+			Comments: []pdns.Comment{owner, {Content: "INC-1234"}}},
+	}}
+	// a search for 10.0.0.1 exports one record and the comment it matched
+	exported := []pdns.RRSet{
+		{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}}, Comments: []pdns.Comment{{Content: "primary firewall"}}},
+	}
+
+	if changes := Plan(zone, exported, false); len(changes) != 0 {
+		t.Errorf("expected no changes without prune, got %+v", changes)
+	}
+
+	changed := []pdns.RRSet{
+		{Name: "fw.example.com.", Type: "A", TTL: 60, Records: []pdns.Record{{Content: "10.0.0.1"}}, Comments: []pdns.Comment{{Content: "primary firewall"}}},
+	}
+	changes := Plan(zone, changed, false)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %+v", changes)
+	}
+	if after := changes[0].After; after.TTL != 60 || len(after.Records) != 2 || len(after.Comments) != 2 {
+		t.Errorf("expected the unlisted record and comment to be kept, got %+v", after)
+	}
+
+	changes = Plan(zone, exported, true)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change with prune, got %+v", changes)
+	}
+	if after := changes[0].After; len(after.Records) != 1 || len(after.Comments) != 1 || after.Comments[0] != owner {
+		t.Errorf("expected the unlisted record and comment to be removed, got %+v", after)
+	}
+}
+
+func TestPlanPruneRemovesComments(t *testing.T) {
+	zone := &pdns.Zone{Name: "example.com.", RRSets: []pdns.RRSet{
+		{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}},
+			Comments: []pdns.Comment{{Content: "primary firewall", Account: "alice"}}},
+	}}
+	desired := []pdns.RRSet{
+		{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}}},
+	}
+
+	if changes := Plan(zone, desired, false); len(changes) != 0 {
+		t.Errorf("expected the comments to be kept without prune, got %+v", changes)
+	}
+
+	changes := Plan(zone, desired, true)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change with prune, got %+v", changes)
+	}
+	if comments := changes[0].After.Comments; comments == nil || len(comments) != 0 {
+		t.Errorf("expected an empty comment list deleting the comments, got %#v", comments)
+	}
+}
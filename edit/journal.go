@@ -0,0 +1,201 @@
+package edit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/mitchellh/go-homedir"
+)
+
+// JournalEntry is an operation that changed records. The changes hold the
+// full state of the touched RRsets before, so the operation can be undone.
+type JournalEntry struct {
+	ID      int                `json:"id"`
+	Time    time.Time          `json:"time"`
+	User    string             `json:"user"`
+	Command string             `json:"command"`
+	Undoes  int                `json:"undoes,omitempty"`
+	Changes []pdns.RRSetChange `json:"changes"`
+}
+
+// Zones returns the zones changed by the entry in order of appearance.
+func (e JournalEntry) Zones() []string {
+	var zones []string
+	for _, c := range e.Changes {
+		if !slices.Contains(zones, c.Zone) {
+			zones = append(zones, c.Zone)
+		}
+	}
+	return zones
+}
+
+// Journal is a file with one JSON encoded entry per line.
+type Journal struct {
+	Path string
+}
+
+// DefaultJournalPath returns pdnsgrep/journal in $XDG_STATE_HOME, which
+// defaults to ~/.local/state.
+func DefaultJournalPath() (string, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", err
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateHome, "pdnsgrep", "journal"), nil
+}
+
+// Entries returns all entries of the journal, oldest first. A missing
+// journal has no entries.
+func (j *Journal) Entries() ([]JournalEntry, error) {
+	f, err := os.Open(j.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("decoding journal %s line %d: %w", j.Path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Entry returns the entry with the id.
+func (j *Journal) Entry(id int) (*JournalEntry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, fmt.Errorf("no journal entry %d", id)
+}
+
+// Append adds the entry with the next free ID to the journal and returns
+// the ID.
+func (j *Journal) Append(entry JournalEntry) (int, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return 0, err
+	}
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return 0, fmt.Errorf("encoding journal entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(j.Path), 0o700); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(j.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return entry.ID, f.Close()
+}
+
+// LastUndoable returns the latest entry that is neither an undo nor undone
+// already, so repeated undos step back through the journal.
+func LastUndoable(entries []JournalEntry) (*JournalEntry, bool) {
+	undone := make(map[int]bool)
+	for _, e := range entries {
+		if e.Undoes != 0 {
+			undone[e.Undoes] = true
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Undoes == 0 && !undone[entries[i].ID] {
+			return &entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// Inverse returns the changes undoing the changes of the entry, last change
+// first. The changes restore the comments of the RRsets as well.
+func (e JournalEntry) Inverse() []pdns.RRSetChange {
+	inverse := make([]pdns.RRSetChange, 0, len(e.Changes))
+	for _, c := range slices.Backward(e.Changes) {
+		inverse = append(inverse, pdns.RRSetChange{Zone: c.Zone, Before: c.After, After: c.Before})
+	}
+	return inverse
+}
+
+// UndoChanges returns the changes restoring the RRsets before the entry,
+// diffed against the current zones by name. RRsets already in the state
+// before the entry are skipped. Changes of RRsets changed since the entry
+// are returned as conflicts as well.
+func (e JournalEntry) UndoChanges(zones map[string]*pdns.Zone) (changes, conflicts []pdns.RRSetChange, err error) {
+	for _, c := range e.Inverse() {
+		zone, ok := zones[c.Zone]
+		if !ok {
+			return nil, nil, fmt.Errorf("zone %s of journal entry %d is missing", c.Zone, e.ID)
+		}
+
+		rrset := c.Before
+		if rrset == nil {
+			rrset = c.After
+		}
+		current, exists := zone.FindRRSet(rrset.Name, rrset.Type)
+		if matchesJournal(c.After, current, exists) {
+			continue
+		}
+
+		change := pdns.RRSetChange{Zone: c.Zone, After: c.After}
+		if exists {
+			change.Before = &current
+		}
+		changes = append(changes, change)
+		if !matchesJournal(c.Before, current, exists) {
+			conflicts = append(conflicts, change)
+		}
+	}
+	return changes, conflicts, nil
+}
+
+// matchesJournal reports whether the current RRset is still in the state
+// recorded by the journal. Recorded comments of nil were kept by the change
+// and match any comments.
+func matchesJournal(recorded *pdns.RRSet, current pdns.RRSet, exists bool) bool {
+	if recorded == nil || !exists {
+		return recorded == nil && !exists
+	}
+	expected := *recorded
+	if expected.Comments == nil {
+		expected.Comments = current.Comments
+	}
+	return current.Equal(expected)
+}
@@ -0,0 +1,147 @@
+package edit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+func TestJournalAppend(t *testing.T) {
+	j := &Journal{Path: filepath.Join(t.TempDir(), "pdnsgrep", "journal")}
+
+	entries, err := j.Entries()
+	if err != nil || entries != nil {
+		t.Fatalf("expected no entries for missing journal, got %v, %v", entries, err)
+	}
+
+	before := &pdns.RRSet{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}}, Comments: []pdns.Comment{}}
+	after := &pdns.RRSet{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.2"}}}
+	for i := range 2 {
+		id, err := j.Append(JournalEntry{
+			Time:    time.Date(2024, 5, 2, 14, 30, i, 0, time.UTC),
+			User:    "alice",
+			Command: "pdnsgrep set fw.example.com. A 300 10.0.0.2",
+			Changes: []pdns.RRSetChange{{Zone: "example.com.", Before: before, After: after}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id != i+1 {
+			t.Errorf("expected id %d, got %d", i+1, id)
+		}
+	}
+
+	entry, err := j.Entry(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := entry.Changes[0]
+	if entry.User != "alice" || c.Zone != "example.com." || c.Before.Records[0].Content != "10.0.0.1" || c.After.Records[0].Content != "10.0.0.2" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	// an empty comment list clears the comments on undo and must survive
+	if c.Before.Comments == nil || c.After.Comments != nil {
+		t.Errorf("expected comments [] and nil, got %v and %v", c.Before.Comments, c.After.Comments)
+	}
+
+	if _, err := j.Entry(3); err == nil {
+		t.Error("expected error for unknown entry, got nil")
+	}
+}
+
+func TestLastUndoable(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []JournalEntry
+		expected int
+	}{
+		{"empty", nil, 0},
+		{"last", []JournalEntry{{ID: 1}, {ID: 2}}, 2},
+		{"skips undone", []JournalEntry{{ID: 1}, {ID: 2}, {ID: 3, Undoes: 2}}, 1},
+		{"all undone", []JournalEntry{{ID: 1}, {ID: 2, Undoes: 1}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := LastUndoable(tt.entries)
+			got := 0
+			if ok {
+				got = entry.ID
+			}
+			if got != tt.expected {
+				t.Errorf("expected entry %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestJournalEntryInverse(t *testing.T) {
+	old := &pdns.RRSet{Name: "fw-1.example.com.", Type: "A", TTL: 300}
+	renamed := &pdns.RRSet{Name: "fw-2.example.com.", Type: "A", TTL: 300}
+	entry := JournalEntry{Changes: []pdns.RRSetChange{
+		{Zone: "example.com.", Before: old},
+		{Zone: "example.com.", After: renamed},
+	}}
+
+	inverse := entry.Inverse()
+	if len(inverse) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(inverse))
+	}
+	if inverse[0].Before != renamed || inverse[0].After != nil {
+		t.Errorf("expected deletion of the renamed RRset first, got %+v", inverse[0])
+	}
+	if inverse[1].Before != nil || inverse[1].After != old {
+		t.Errorf("expected creation of the old RRset, got %+v", inverse[1])
+	}
+}
+
+func TestJournalEntryUndoChanges(t *testing.T) {
+	before := &pdns.RRSet{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.1"}}, Comments: []pdns.Comment{}}
+	after := &pdns.RRSet{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.2"}}, Comments: []pdns.Comment{}}
+	// a change keeping the comments records them as nil
+	keptComments := &pdns.RRSet{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.2"}}}
+	commented := pdns.RRSet{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.2"}}, Comments: []pdns.Comment{{Content: "INC-1234"}}}
+	changedSince := pdns.RRSet{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []pdns.Record{{Content: "10.0.0.3"}}}
+
+	tests := []struct {
+		name      string
+		after     *pdns.RRSet
+		current   []pdns.RRSet
+		changes   int
+		conflicts int
+	}{
+		{"unchanged", after, []pdns.RRSet{*after}, 1, 0},
+		{"undone already", after, []pdns.RRSet{*before}, 0, 0},
+		{"changed since", after, []pdns.RRSet{changedSince}, 1, 1},
+		{"deleted since", after, nil, 1, 1},
+		{"kept comments", keptComments, []pdns.RRSet{commented}, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := JournalEntry{ID: 3, Changes: []pdns.RRSetChange{{Zone: "example.com.", Before: before, After: tt.after}}}
+			zones := map[string]*pdns.Zone{"example.com.": {Name: "example.com.", RRSets: tt.current}}
+
+			changes, conflicts, err := entry.UndoChanges(zones)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(changes) != tt.changes || len(conflicts) != tt.conflicts {
+				t.Fatalf("expected %d changes and %d conflicts, got %+v and %+v", tt.changes, tt.conflicts, changes, conflicts)
+			}
+			if len(changes) > 0 && changes[0].After != before {
+				t.Errorf("expected the RRset before the entry to be restored, got %+v", changes[0].After)
+			}
+			if len(changes) > 0 && (changes[0].Before == nil) != (tt.current == nil) {
+				t.Errorf("expected the current RRset as before, got %+v", changes[0].Before)
+			}
+		})
+	}
+
+	entry := JournalEntry{ID: 3, Changes: []pdns.RRSetChange{{Zone: "example.com.", Before: before, After: after}}}
+	if _, _, err := entry.UndoChanges(nil); err == nil {
+		t.Error("expected error for missing zone")
+	}
+}
@@ -0,0 +1,75 @@
+package edit
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+// DisabledByPrefix starts the comments added when records are disabled.
+const DisabledByPrefix = "disabled by "
+
+// Toggle disables or enables records and notes it in a comment of their
+// RRset.
+type Toggle struct {
+	// Disabled is the flag set on the records.
+	Disabled bool
+	// User and Reason are named in the comment.
+	User   string
+	Reason string
+	// Account and Time are set on the comment.
+	Account string
+	Time    time.Time
+}
+
+// Change returns the change setting the disabled flag of the records of
+// rrset for which match returns true, other records are left as they are.
+// It reports false if no record needs to change.
+func (t Toggle) Change(zone string, rrset pdns.RRSet, match func(pdns.Record) bool) (pdns.RRSetChange, bool) {
+	after := rrset
+	after.Records = slices.Clone(rrset.Records)
+	toggled := 0
+	for i, r := range after.Records {
+		if r.Disabled != t.Disabled && match(r) {
+			after.Records[i].Disabled = t.Disabled
+			toggled++
+		}
+	}
+	if toggled == 0 {
+		return pdns.RRSetChange{}, false
+	}
+	after.Comments = t.comments(after, rrset.Comments)
+	return pdns.RRSetChange{Zone: zone, Before: &rrset, After: &after}, true
+}
+
+// comments returns the comments of rrset after its records were disabled or
+// enabled. Disabling adds a comment with the user and reason. Enabling
+// removes these comments once no record is disabled anymore and adds a
+// comment if a reason is given.
+func (t Toggle) comments(rrset pdns.RRSet, comments []pdns.Comment) []pdns.Comment {
+	action := "enabled by "
+	result := make([]pdns.Comment, 0, len(comments)+1)
+	if t.Disabled {
+		action = DisabledByPrefix
+		result = append(result, comments...)
+	} else {
+		stillDisabled := slices.ContainsFunc(rrset.Records, func(r pdns.Record) bool { return r.Disabled })
+		for _, c := range comments {
+			if stillDisabled || !strings.HasPrefix(c.Content, DisabledByPrefix) {
+				result = append(result, c)
+			}
+		}
+		if t.Reason == "" {
+			return result
+		}
+	}
+
+	content := action + t.User
+	if t.Reason != "" {
+		content = fmt.Sprintf("%s%s: %s", action, t.User, t.Reason)
+	}
+	return append(result, pdns.Comment{Content: content, Account: t.Account, ModifiedAt: t.Time.Unix()})
+}
@@ -0,0 +1,119 @@
+package edit
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+func TestToggleChange(t *testing.T) {
+	at := time.Date(2024, 5, 2, 14, 30, 0, 0, time.UTC)
+	owner := pdns.Comment{Content: "primary firewall", Account: "alice"}
+	disabledBy := pdns.Comment{Content: "disabled by bob: INC-1234", Account: "bob"}
+	matchFirst := func(r pdns.Record) bool { return r.Content == "10.0.0.1" }
+	matchAll := func(pdns.Record) bool { return true }
+
+	tests := []struct {
+		name     string
+		toggle   Toggle
+		records  []pdns.Record
+		comments []pdns.Comment
+		match    func(pdns.Record) bool
+		disabled []bool
+		expected []string
+	}{
+		{
+			name:     "disable with reason",
+			toggle:   Toggle{Disabled: true, User: "bob", Reason: "INC-1234", Account: "netops", Time: at},
+			records:  []pdns.Record{{Content: "10.0.0.1"}, {Content: "10.0.0.2"}},
+			comments: []pdns.Comment{owner},
+			match:    matchFirst,
+			disabled: []bool{true, false},
+			expected: []string{"primary firewall", "disabled by bob: INC-1234"},
+		},
+		{
+			name:     "enable keeps the comment while records are disabled",
+			toggle:   Toggle{User: "bob", Time: at},
+			records:  []pdns.Record{{Content: "10.0.0.1", Disabled: true}, {Content: "10.0.0.2", Disabled: true}},
+			comments: []pdns.Comment{owner, disabledBy},
+			match:    matchFirst,
+			disabled: []bool{false, true},
+			expected: []string{"primary firewall", "disabled by bob: INC-1234"},
+		},
+		{
+			name:     "enable removes the comment once all records are enabled",
+			toggle:   Toggle{User: "bob", Time: at},
+			records:  []pdns.Record{{Content: "10.0.0.1", Disabled: true}, {Content: "10.0.0.2", Disabled: true}},
+			comments: []pdns.Comment{owner, disabledBy},
+			match:    matchAll,
+			disabled: []bool{false, false},
+			expected: []string{"primary firewall"},
+		},
+		{
+			name:     "enable with reason",
+			toggle:   Toggle{User: "bob", Reason: "fixed", Time: at},
+			records:  []pdns.Record{{Content: "10.0.0.1", Disabled: true}},
+			comments: []pdns.Comment{disabledBy},
+			match:    matchAll,
+			disabled: []bool{false},
+			expected: []string{"enabled by bob: fixed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rrset := pdns.RRSet{Name: "fw.example.com.", Type: "A", TTL: 300, Records: tt.records, Comments: tt.comments}
+			original := slices.Clone(tt.records)
+			change, ok := tt.toggle.Change("example.com.", rrset, tt.match)
+			if !ok {
+				t.Fatal("expected a change")
+			}
+			if change.Zone != "example.com." || !slices.Equal(change.Before.Records, original) {
+				t.Errorf("expected the RRset before the change, got %+v", change.Before)
+			}
+			for i, r := range change.After.Records {
+				if r.Disabled != tt.disabled[i] {
+					t.Errorf("expected record %s disabled=%t, got %t", r.Content, tt.disabled[i], r.Disabled)
+				}
+			}
+
+			var got []string
+			for _, c := range change.After.Comments {
+				got = append(got, c.Content)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected comments %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("expected comments %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestToggleCommentAccount(t *testing.T) {
+	at := time.Date(2024, 5, 2, 14, 30, 0, 0, time.UTC)
+	toggle := Toggle{Disabled: true, User: "bob", Account: "netops", Time: at}
+	rrset := pdns.RRSet{Name: "fw.example.com.", Type: "A", Records: []pdns.Record{{Content: "10.0.0.1"}}}
+
+	change, _ := toggle.Change("example.com.", rrset, func(pdns.Record) bool { return true })
+	comment := change.After.Comments[0]
+	if comment.Content != "disabled by bob" || comment.Account != "netops" || comment.ModifiedAt != at.Unix() {
+		t.Errorf("unexpected comment %+v", comment)
+	}
+}
+
+func TestToggleNoChange(t *testing.T) {
+	rrset := pdns.RRSet{Name: "fw.example.com.", Type: "A", Records: []pdns.Record{{Content: "10.0.0.1", Disabled: true}}}
+	if _, ok := (Toggle{Disabled: true}).Change("example.com.", rrset, func(pdns.Record) bool { return true }); ok {
+		t.Error("expected no change for an already disabled record")
+	}
+	if _, ok := (Toggle{}).Change("example.com.", rrset, func(pdns.Record) bool { return false }); ok {
+		t.Error("expected no change without matching records")
+	}
+}
@@ -0,0 +1,62 @@
+// Package edit holds the changes and the files written and read by the
+// commands changing records.
+package edit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SavedTTL is the TTL an RRset had before a bulk TTL change.
+type SavedTTL struct {
+	Zone string `json:"zone"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  int    `json:"ttl"`
+}
+
+// TTLFile holds the previous TTLs of the RRsets changed by a bulk TTL
+// change, so they can be restored later.
+type TTLFile struct {
+	SavedAt time.Time  `json:"saved_at"`
+	RRSets  []SavedTTL `json:"rrsets"`
+}
+
+// SaveTTLs writes the TTLs to path. An existing file is never overwritten,
+// as it might hold the original TTLs of an earlier change.
+func SaveTTLs(path string, ttls TTLFile) error {
+	data, err := json.MarshalIndent(ttls, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding TTLs: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, restore or remove it first", path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadTTLs reads the TTLs saved by SaveTTLs from path.
+func LoadTTLs(path string) (*TTLFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ttls TTLFile
+	if err := json.Unmarshal(data, &ttls); err != nil {
+		return nil, fmt.Errorf("decoding TTL file %s: %w", path, err)
+	}
+	return &ttls, nil
+}
@@ -0,0 +1,54 @@
+package edit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTTLsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ttls.json")
+
+	ttls := TTLFile{
+		SavedAt: time.Date(2024, 5, 2, 14, 30, 0, 0, time.UTC),
+		RRSets: []SavedTTL{
+			{Zone: "example.com.", Name: "fw.example.com.", Type: "A", TTL: 3600},
+			{Zone: "example.com.", Name: "example.com.", Type: "MX", TTL: 86400},
+		},
+	}
+	if err := SaveTTLs(path, ttls); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := LoadTTLs(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded.RRSets) != 2 || loaded.RRSets[1] != ttls.RRSets[1] {
+		t.Errorf("unexpected TTLs: %+v", loaded)
+	}
+	if !loaded.SavedAt.Equal(ttls.SavedAt) {
+		t.Errorf("expected saved at %v, got %v", ttls.SavedAt, loaded.SavedAt)
+	}
+}
+
+func TestSaveTTLsKeepsExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ttls.json")
+
+	original := TTLFile{RRSets: []SavedTTL{{Zone: "example.com.", Name: "fw.example.com.", Type: "A", TTL: 3600}}}
+	if err := SaveTTLs(path, original); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lowered := TTLFile{RRSets: []SavedTTL{{Zone: "example.com.", Name: "fw.example.com.", Type: "A", TTL: 60}}}
+	if err := SaveTTLs(path, lowered); err == nil {
+		t.Error("expected error for existing file, got nil")
+	}
+
+	loaded, err := LoadTTLs(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.RRSets[0].TTL != 3600 {
+		t.Errorf("expected original TTL 3600, got %d", loaded.RRSets[0].TTL)
+	}
+}
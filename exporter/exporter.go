@@ -0,0 +1,172 @@
+// Package exporter serves the results of configured searches as Prometheus
+// metrics.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/akquinet/pdnsgrep/watch"
+	log "github.com/sirupsen/logrus"
+)
+
+// queryState holds the metrics of a single query.
+type queryState struct {
+	counts     map[string]int
+	records    []pdns.PDNSSearchResponseItem
+	polled     bool
+	latency    *histogram
+	errors     uint64
+	lastChange time.Time
+}
+
+// Exporter polls the queries and serves their results as metrics.
+type Exporter struct {
+	Client  *pdns.PDNSAPI
+	Queries []watch.Query
+	// Concurrency limits the number of searches running at the same time.
+	Concurrency int
+
+	once   sync.Once
+	limit  chan struct{}
+	mu     sync.Mutex
+	states map[string]*queryState
+}
+
+func (e *Exporter) init() {
+	e.once.Do(func() {
+		e.limit = make(chan struct{}, max(e.Concurrency, 1))
+		e.states = make(map[string]*queryState)
+		for _, q := range e.Queries {
+			e.states[q.Name] = &queryState{latency: newHistogram()}
+		}
+	})
+}
+
+// zoneTypeKey counts records per zone and type with misc.CountBy.
+func zoneTypeKey(r pdns.PDNSSearchResponseItem) string {
+	return r.Zone + "\x00" + r.Type
+}
+
+// Poll runs the search of q once and updates its metrics.
+func (e *Exporter) Poll(ctx context.Context, q watch.Query) {
+	e.init()
+
+	select {
+	case e.limit <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	start := time.Now()
+	found, err := pdns.GetPDNSRecords(ctx, e.Client, q.Terms, q.ObjectType)
+	elapsed := time.Since(start)
+	<-e.limit
+
+	if err == nil && q.Type != "" {
+		found = pdns.FilterRecordsOnType(found, q.Type)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.states[q.Name]
+	state.latency.observe(elapsed.Seconds())
+	if err != nil {
+		log.Errorf("[%s] %v", q.Name, err)
+		state.errors++
+		return
+	}
+
+	if state.polled && len(misc.DiffRRsets(state.records, found)) > 0 {
+		state.lastChange = time.Now()
+	}
+	state.polled = true
+	state.records = found
+	state.counts = misc.CountBy(found, zoneTypeKey)
+}
+
+// PollAll runs the search of every query once.
+func (e *Exporter) PollAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, q := range e.Queries {
+		wg.Go(func() { e.Poll(ctx, q) })
+	}
+	wg.Wait()
+}
+
+// Run polls every query on its own interval until ctx ends.
+func (e *Exporter) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, q := range e.Queries {
+		wg.Go(func() {
+			ticker := time.NewTicker(q.Interval)
+			defer ticker.Stop()
+			for {
+				e.Poll(ctx, q)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	e.WriteMetrics(&buf)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// WriteMetrics writes the metrics of all queries in the Prometheus text
+// format.
+func (e *Exporter) WriteMetrics(buf *bytes.Buffer) {
+	e.init()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	writeHeader(buf, "pdnsgrep_records", "gauge", "Number of records found by the query per zone and type.")
+	for _, q := range e.Queries {
+		counts := e.states[q.Name].counts
+		keys := make([]string, 0, len(counts))
+		for k := range counts {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			zone, rType, _ := strings.Cut(k, "\x00")
+			labels := []label{{"query", q.Name}, {"zone", zone}, {"type", rType}}
+			writeSample(buf, "pdnsgrep_records", labels, strconv.Itoa(counts[k]))
+		}
+	}
+
+	writeHeader(buf, "pdnsgrep_search_duration_seconds", "histogram", "Duration of the searches of the query.")
+	for _, q := range e.Queries {
+		writeHistogram(buf, "pdnsgrep_search_duration_seconds", []label{{"query", q.Name}}, e.states[q.Name].latency)
+	}
+
+	writeHeader(buf, "pdnsgrep_api_errors_total", "counter", "Number of failed searches of the query.")
+	for _, q := range e.Queries {
+		writeSample(buf, "pdnsgrep_api_errors_total", []label{{"query", q.Name}}, strconv.FormatUint(e.states[q.Name].errors, 10))
+	}
+
+	writeHeader(buf, "pdnsgrep_last_change_timestamp_seconds", "gauge", "Unix time of the last change in the results of the query, 0 if none was seen yet.")
+	for _, q := range e.Queries {
+		var ts float64
+		if last := e.states[q.Name].lastChange; !last.IsZero() {
+			ts = float64(last.UnixMilli()) / 1000
+		}
+		writeSample(buf, "pdnsgrep_last_change_timestamp_seconds", []label{{"query", q.Name}}, formatFloat(ts))
+	}
+}
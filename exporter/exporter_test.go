@@ -0,0 +1,122 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/akquinet/pdnsgrep/watch"
+)
+
+// fakePDNS answers searches for *fw* and fails all other searches. After
+// the first search the content of fw-1 changes.
+func fakePDNS(t *testing.T) *httptest.Server {
+	t.Helper()
+	var calls atomic.Int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "*fw*" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		content := "10.0.0.1"
+		if calls.Add(1) > 1 {
+			content = "10.0.0.9"
+		}
+		json.NewEncoder(w).Encode([]pdns.PDNSSearchResponseItem{
+			{Name: "fw-1.example.com.", Type: "A", Content: content, ObjectType: "record", Zone: "example.com.", Ttl: 300},
+			{Name: "fw-2.example.com.", Type: "A", Content: "10.0.0.2", ObjectType: "record", Zone: "example.com.", Ttl: 300},
+			{Name: "fw.example.org.", Type: "AAAA", Content: "2001:db8::1", ObjectType: "record", Zone: "example.org.", Ttl: 300},
+		})
+	}))
+}
+
+func scrape(t *testing.T, e *Exporter) string {
+	t.Helper()
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected text/plain, got %s", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(body)
+}
+
+func TestExporterMetrics(t *testing.T) {
+	pdnsServer := fakePDNS(t)
+	defer pdnsServer.Close()
+
+	e := &Exporter{
+		Client: pdns.NewPDNSAPI(pdnsServer.URL, "secret"),
+		Queries: []watch.Query{
+			{Name: "firewalls", Terms: []string{"fw"}, ObjectType: "all"},
+			{Name: "broken", Terms: []string{"ns1.example.com."}, ObjectType: "all"},
+		},
+		Concurrency: 2,
+	}
+	e.PollAll(context.Background())
+
+	metrics := scrape(t, e)
+	for _, expected := range []string{
+		"# TYPE pdnsgrep_records gauge",
+		`pdnsgrep_records{query="firewalls",zone="example.com.",type="A"} 2`,
+		`pdnsgrep_records{query="firewalls",zone="example.org.",type="AAAA"} 1`,
+		"# TYPE pdnsgrep_search_duration_seconds histogram",
+		`pdnsgrep_search_duration_seconds_bucket{query="firewalls",le="+Inf"} 1`,
+		`pdnsgrep_search_duration_seconds_count{query="broken"} 1`,
+		`pdnsgrep_api_errors_total{query="firewalls"} 0`,
+		`pdnsgrep_api_errors_total{query="broken"} 1`,
+		`pdnsgrep_last_change_timestamp_seconds{query="firewalls"} 0`,
+	} {
+		if !strings.Contains(metrics, expected) {
+			t.Errorf("expected %q in metrics, got:\n%s", expected, metrics)
+		}
+	}
+
+	// the second search returns another content for fw-1
+	e.Poll(context.Background(), e.Queries[0])
+	metrics = scrape(t, e)
+	if strings.Contains(metrics, `pdnsgrep_last_change_timestamp_seconds{query="firewalls"} 0`+"\n") {
+		t.Errorf("expected last change timestamp to be set, got:\n%s", metrics)
+	}
+	if !strings.Contains(metrics, `pdnsgrep_search_duration_seconds_count{query="firewalls"} 2`) {
+		t.Errorf("expected 2 observed searches, got:\n%s", metrics)
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	got := formatLabels([]label{{"query", `a "b"`}, {"zone", `c\d`}})
+	expected := `{query="a \"b\"",zone="c\\d"}`
+	if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestHistogramObserve(t *testing.T) {
+	h := newHistogram()
+	h.observe(0.02)
+	h.observe(3)
+	h.observe(20)
+
+	if h.count != 3 || h.sum != 23.02 {
+		t.Errorf("expected count 3 and sum 23.02, got %d and %v", h.count, h.sum)
+	}
+	// buckets are cumulative: 0.025 holds 0.02, 5 holds 0.02 and 3
+	if h.counts[2] != 1 || h.counts[9] != 2 || h.counts[len(h.counts)-1] != 2 {
+		t.Errorf("unexpected bucket counts %v", h.counts)
+	}
+}
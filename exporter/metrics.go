@@ -0,0 +1,78 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// latencyBuckets are the upper bounds of the search latency histogram in
+// seconds.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram counts observations in cumulative buckets like a Prometheus
+// histogram.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(latencyBuckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range latencyBuckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// label is a single label of a metric sample.
+type label struct {
+	name, value string
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels []label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.name + `="` + labelEscaper.Replace(l.value) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric.
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func writeSample(w io.Writer, name string, labels []label, value string) {
+	fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(labels), value)
+}
+
+// writeHistogram writes the bucket, sum and count samples of h.
+func writeHistogram(w io.Writer, name string, labels []label, h *histogram) {
+	for i, bound := range latencyBuckets {
+		le := append(labels[:len(labels):len(labels)], label{"le", formatFloat(bound)})
+		writeSample(w, name+"_bucket", le, strconv.FormatUint(h.counts[i], 10))
+	}
+	inf := append(labels[:len(labels):len(labels)], label{"le", "+Inf"})
+	writeSample(w, name+"_bucket", inf, strconv.FormatUint(h.count, 10))
+	writeSample(w, name+"_sum", labels, formatFloat(h.sum))
+	writeSample(w, name+"_count", labels, strconv.FormatUint(h.count, 10))
+}
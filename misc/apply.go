@@ -0,0 +1,20 @@
+package misc
+
+// ZoneResult is the outcome of applying the RRset changes of one zone.
+type ZoneResult struct {
+	Zone    string
+	Changes int
+	Err     error
+}
+
+// OutputZoneResults prints the applied zones in green and the failed zones
+// with their error in red.
+func OutputZoneResults(results []ZoneResult) {
+	for _, r := range results {
+		if r.Err != nil {
+			removeColor.Printf("failed  %s (%d RRset changes): %v\n", r.Zone, r.Changes, r.Err)
+			continue
+		}
+		addColor.Printf("applied %s (%d RRset changes)\n", r.Zone, r.Changes)
+	}
+}
@@ -0,0 +1,242 @@
+package misc
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// RecordChange describes how an RRset changed between two result sets.
+// Before is nil for added RRsets, After is nil for removed ones.
+type RecordChange struct {
+	Kind   ChangeKind `json:"kind"`
+	Zone   string     `json:"zone"`
+	Name   string     `json:"name"`
+	Type   string     `json:"type"`
+	Before *RRset     `json:"before,omitempty"`
+	After  *RRset     `json:"after,omitempty"`
+}
+
+// TTLChanged reports whether a modification changed the TTL.
+func (c RecordChange) TTLChanged() bool {
+	return c.Before != nil && c.After != nil && c.Before.Ttl != c.After.Ttl
+}
+
+// ContentsChanged reports whether a modification changed the contents.
+func (c RecordChange) ContentsChanged() bool {
+	return len(c.AddedContents()) > 0 || len(c.RemovedContents()) > 0
+}
+
+// AddedContents returns the contents only present after the change.
+func (c RecordChange) AddedContents() []string {
+	return contentsMissing(c.After, c.Before)
+}
+
+// RemovedContents returns the contents only present before the change.
+func (c RecordChange) RemovedContents() []string {
+	return contentsMissing(c.Before, c.After)
+}
+
+// AddedComments returns the comments only present after the change.
+func (c RecordChange) AddedComments() []pdns.Comment {
+	return commentsMissing(c.After, c.Before)
+}
+
+// RemovedComments returns the comments only present before the change.
+func (c RecordChange) RemovedComments() []pdns.Comment {
+	return commentsMissing(c.Before, c.After)
+}
+
+// contentsMissing returns the contents of a that are not in b.
+func contentsMissing(a, b *RRset) []string {
+	if a == nil {
+		return nil
+	}
+	var missing []string
+	for _, content := range a.Contents {
+		if b == nil || !slices.Contains(b.Contents, content) {
+			missing = append(missing, content)
+		}
+	}
+	return missing
+}
+
+// commentsMissing returns the comments of a that are not in b.
+func commentsMissing(a, b *RRset) []pdns.Comment {
+	if a == nil {
+		return nil
+	}
+	var missing []pdns.Comment
+	for _, comment := range a.Comments {
+		if b == nil || !slices.Contains(b.Comments, comment) {
+			missing = append(missing, comment)
+		}
+	}
+	return missing
+}
+
+// rrsetIdentity identifies an RRset across result sets. The zone is part of
+// it, as a delegation NS or glue record in the parent zone shares name and
+// type with an RRset of the child zone.
+func rrsetIdentity(rr RRset) string {
+	return fmt.Sprintf("%s|%s|%s|%s", asciiLower(rr.Zone), asciiLower(rr.Name), strings.ToUpper(rr.Type), rr.ObjectType)
+}
+
+func sameContents(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// markDisabled returns the records with the contents of disabled records
+// marked, so enabling or disabling a record changes its content in a diff.
+func markDisabled(records []pdns.PDNSSearchResponseItem) []pdns.PDNSSearchResponseItem {
+	if !slices.ContainsFunc(records, func(r pdns.PDNSSearchResponseItem) bool { return r.Disabled }) {
+		return records
+	}
+	marked := slices.Clone(records)
+	for i := range marked {
+		if marked[i].Disabled {
+			marked[i].Content += disabledMarker
+		}
+	}
+	return marked
+}
+
+// DiffRRsets compares the records per RRset (zone, name, type and object
+// type, see rrsetIdentity). TTL, content and comment changes of an existing
+// RRset are reported as modifications, a disabled record is a content ending
+// with " [disabled]". The changes are ordered by name, type, zone, object
+// type and kind.
+func DiffRRsets(prev, curr []pdns.PDNSSearchResponseItem) []RecordChange {
+	prevSets := make(map[string]RRset)
+	for _, rr := range GroupRRsets(markDisabled(prev)) {
+		prevSets[rrsetIdentity(rr)] = rr
+	}
+	currSets := make(map[string]RRset)
+	for _, rr := range GroupRRsets(markDisabled(curr)) {
+		currSets[rrsetIdentity(rr)] = rr
+	}
+
+	var changes []RecordChange
+	for key, before := range prevSets {
+		after, ok := currSets[key]
+		switch {
+		case !ok:
+			changes = append(changes, RecordChange{Kind: ChangeRemoved, Zone: before.Zone, Name: before.Name, Type: before.Type, Before: &before})
+		case before.Ttl != after.Ttl || !sameContents(before.Contents, after.Contents) || !slices.Equal(before.Comments, after.Comments):
+			changes = append(changes, RecordChange{Kind: ChangeModified, Zone: after.Zone, Name: after.Name, Type: after.Type, Before: &before, After: &after})
+		}
+	}
+	for key, after := range currSets {
+		if _, ok := prevSets[key]; !ok {
+			changes = append(changes, RecordChange{Kind: ChangeAdded, Zone: after.Zone, Name: after.Name, Type: after.Type, After: &after})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b RecordChange) int {
+		if c := CompareDNSNames(a.Name, b.Name); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Type, b.Type); c != 0 {
+			return c
+		}
+		if c := CompareDNSNames(a.Zone, b.Zone); c != 0 {
+			return c
+		}
+		// records and comments of an RRset share name, type and zone
+		if c := strings.Compare(a.objectType(), b.objectType()); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.Kind), string(b.Kind))
+	})
+	return changes
+}
+
+// objectType returns the object type of the changed RRset.
+func (c RecordChange) objectType() string {
+	if c.After != nil {
+		return c.After.ObjectType
+	}
+	return c.Before.ObjectType
+}
+
+func formatRRsetRecords(rr *RRset, contents []string, prefix string) []string {
+	lines := make([]string, 0, len(contents))
+	for _, content := range contents {
+		r := pdns.PDNSSearchResponseItem{Zone: rr.Zone, Name: rr.Name, Type: rr.Type, Content: content, Ttl: rr.Ttl, ObjectType: rr.ObjectType}
+		lines = append(lines, prefix+formatRecord(r, SpaceDelimiter))
+	}
+	return lines
+}
+
+// OutputDiff prints removed records in red, added records in green and
+// modified RRsets in yellow with their old and new values. Comments are
+// prefixed with "; ".
+func OutputDiff(changes []RecordChange) {
+	for _, c := range changes {
+		switch c.Kind {
+		case ChangeRemoved:
+			for _, line := range formatRRsetRecords(c.Before, c.Before.Contents, "- ") {
+				removeColor.Println(line)
+			}
+		case ChangeAdded:
+			for _, line := range formatRRsetRecords(c.After, c.After.Contents, "+ ") {
+				addColor.Println(line)
+			}
+		case ChangeModified:
+			summary := fmt.Sprintf("~ %s %s %s", c.Zone, c.Name, c.Type)
+			if c.TTLChanged() {
+				summary += fmt.Sprintf(" ttl %d -> %d", c.Before.Ttl, c.After.Ttl)
+			}
+			modifyColor.Println(summary)
+			for _, content := range c.RemovedContents() {
+				removeColor.Printf("    - %s\n", content)
+			}
+			for _, content := range c.AddedContents() {
+				addColor.Printf("    + %s\n", content)
+			}
+			for _, comment := range c.RemovedComments() {
+				removeColor.Printf("    - ; %s\n", formatComments([]pdns.Comment{comment}))
+			}
+			for _, comment := range c.AddedComments() {
+				addColor.Printf("    + ; %s\n", formatComments([]pdns.Comment{comment}))
+			}
+		}
+	}
+}
+
+// OutputDiffJSON prints the changes as a single line JSON object, so every
+// update can be consumed as one event. query names the watched query and is
+// omitted if empty.
+func OutputDiffJSON(at time.Time, query string, changes []RecordChange) error {
+	if changes == nil {
+		changes = []RecordChange{}
+	}
+	output, err := json.Marshal(struct {
+		Time    time.Time      `json:"time"`
+		Query   string         `json:"query,omitempty"`
+		Changes []RecordChange `json:"changes"`
+	}{at, query, changes})
+	if err != nil {
+		return fmt.Errorf("marshaling to JSON: %w", err)
+	}
+	fmt.Println(string(output))
+	return nil
+}
@@ -0,0 +1,155 @@
+package misc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+func TestDiffRRsets(t *testing.T) {
+	base := []pdns.PDNSSearchResponseItem{
+		{Zone: "example.com.", Name: "a.example.com.", Type: "A", Content: "10.0.0.1", Ttl: 300, ObjectType: "record"},
+		{Zone: "example.com.", Name: "a.example.com.", Type: "A", Content: "10.0.0.2", Ttl: 300, ObjectType: "record"},
+		{Zone: "example.com.", Name: "b.example.com.", Type: "AAAA", Content: "2001:db8::1", Ttl: 3600, ObjectType: "record"},
+	}
+
+	t.Run("no changes", func(t *testing.T) {
+		reordered := []pdns.PDNSSearchResponseItem{base[2], base[1], base[0]}
+		if changes := DiffRRsets(base, reordered); len(changes) != 0 {
+			t.Errorf("expected no changes, got %v", changes)
+		}
+	})
+
+	t.Run("ttl change is a modification", func(t *testing.T) {
+		curr := []pdns.PDNSSearchResponseItem{base[0], base[1], base[2]}
+		curr[2].Ttl = 60
+		changes := DiffRRsets(base, curr)
+		if len(changes) != 1 || changes[0].Kind != ChangeModified {
+			t.Fatalf("expected 1 modification, got %v", changes)
+		}
+		if !changes[0].TTLChanged() || changes[0].ContentsChanged() {
+			t.Errorf("expected only the TTL to change, got %v", changes[0])
+		}
+		if changes[0].Before.Ttl != 3600 || changes[0].After.Ttl != 60 {
+			t.Errorf("expected ttl 3600 -> 60, got %d -> %d", changes[0].Before.Ttl, changes[0].After.Ttl)
+		}
+	})
+
+	t.Run("content change is a modification", func(t *testing.T) {
+		curr := []pdns.PDNSSearchResponseItem{base[0], base[1], base[2]}
+		curr[1].Content = "10.0.0.3"
+		changes := DiffRRsets(base, curr)
+		if len(changes) != 1 || changes[0].Kind != ChangeModified {
+			t.Fatalf("expected 1 modification, got %v", changes)
+		}
+		if !reflect.DeepEqual(changes[0].AddedContents(), []string{"10.0.0.3"}) {
+			t.Errorf("expected added 10.0.0.3, got %v", changes[0].AddedContents())
+		}
+		if !reflect.DeepEqual(changes[0].RemovedContents(), []string{"10.0.0.2"}) {
+			t.Errorf("expected removed 10.0.0.2, got %v", changes[0].RemovedContents())
+		}
+		if changes[0].TTLChanged() {
+			t.Error("expected TTL to be unchanged")
+		}
+	})
+
+	t.Run("disabling a record is a modification", func(t *testing.T) {
+		curr := []pdns.PDNSSearchResponseItem{base[0], base[1], base[2]}
+		curr[0].Disabled = true
+		changes := DiffRRsets(base, curr)
+		if len(changes) != 1 || changes[0].Kind != ChangeModified {
+			t.Fatalf("expected 1 modification, got %v", changes)
+		}
+		if !reflect.DeepEqual(changes[0].AddedContents(), []string{"10.0.0.1 [disabled]"}) {
+			t.Errorf("expected added 10.0.0.1 [disabled], got %v", changes[0].AddedContents())
+		}
+		if !reflect.DeepEqual(changes[0].RemovedContents(), []string{"10.0.0.1"}) {
+			t.Errorf("expected removed 10.0.0.1, got %v", changes[0].RemovedContents())
+		}
+		if curr[0].Content != "10.0.0.1" {
+			t.Errorf("expected the records to be unchanged, got %v", curr[0])
+		}
+	})
+
+	t.Run("comment change is a modification", func(t *testing.T) {
+		comment := pdns.Comment{Content: "primary firewall", Account: "alice"}
+		curr := []pdns.PDNSSearchResponseItem{base[0], base[1], base[2]}
+		curr[0].Comments = []pdns.Comment{comment}
+		curr[1].Comments = []pdns.Comment{comment}
+		changes := DiffRRsets(base, curr)
+		if len(changes) != 1 || changes[0].Kind != ChangeModified {
+			t.Fatalf("expected 1 modification, got %v", changes)
+		}
+		if changes[0].ContentsChanged() || changes[0].TTLChanged() {
+			t.Errorf("expected only the comments to change, got %v", changes[0])
+		}
+		if !reflect.DeepEqual(changes[0].AddedComments(), []pdns.Comment{comment}) || len(changes[0].RemovedComments()) != 0 {
+			t.Errorf("expected added comment %v, got %v and removed %v", comment, changes[0].AddedComments(), changes[0].RemovedComments())
+		}
+	})
+
+	t.Run("same name and type in two zones", func(t *testing.T) {
+		delegation := []pdns.PDNSSearchResponseItem{
+			{Zone: "example.com.", Name: "sub.example.com.", Type: "NS", Content: "ns1.sub.example.com.", Ttl: 3600, ObjectType: "record"},
+			{Zone: "sub.example.com.", Name: "sub.example.com.", Type: "NS", Content: "ns1.sub.example.com.", Ttl: 3600, ObjectType: "record"},
+			{Zone: "sub.example.com.", Name: "sub.example.com.", Type: "NS", Content: "ns2.sub.example.com.", Ttl: 3600, ObjectType: "record"},
+		}
+		reordered := []pdns.PDNSSearchResponseItem{delegation[1], delegation[2], delegation[0]}
+		if changes := DiffRRsets(delegation, reordered); len(changes) != 0 {
+			t.Errorf("expected no changes, got %v", changes)
+		}
+
+		curr := []pdns.PDNSSearchResponseItem{delegation[0], delegation[1], delegation[2]}
+		curr[0].Content = "ns9.sub.example.com."
+		changes := DiffRRsets(delegation, curr)
+		if len(changes) != 1 || changes[0].Kind != ChangeModified || changes[0].Zone != "example.com." {
+			t.Fatalf("expected the delegation in example.com. to be modified, got %v", changes)
+		}
+		if !reflect.DeepEqual(changes[0].RemovedContents(), []string{"ns1.sub.example.com."}) {
+			t.Errorf("expected removed ns1.sub.example.com., got %v", changes[0].RemovedContents())
+		}
+	})
+
+	t.Run("added and removed RRsets", func(t *testing.T) {
+		curr := []pdns.PDNSSearchResponseItem{
+			base[0], base[1],
+			{Zone: "example.com.", Name: "c.example.com.", Type: "A", Content: "10.0.0.9", Ttl: 300, ObjectType: "record"},
+		}
+		changes := DiffRRsets(base, curr)
+		if len(changes) != 2 {
+			t.Fatalf("expected 2 changes, got %v", changes)
+		}
+		if changes[0].Kind != ChangeRemoved || changes[0].Name != "b.example.com." || changes[0].After != nil {
+			t.Errorf("expected b.example.com. removed, got %v", changes[0])
+		}
+		if changes[1].Kind != ChangeAdded || changes[1].Name != "c.example.com." || changes[1].Before != nil {
+			t.Errorf("expected c.example.com. added, got %v", changes[1])
+		}
+	})
+
+	t.Run("record and comment of an RRset in stable order", func(t *testing.T) {
+		curr := []pdns.PDNSSearchResponseItem{
+			{Zone: "example.com.", Name: "a.example.com.", Type: "A", Content: "primary firewall", ObjectType: "comment"},
+			{Zone: "example.com.", Name: "a.example.com.", Type: "A", Content: "10.0.0.1", Ttl: 300, ObjectType: "record"},
+		}
+		for range 20 {
+			changes := DiffRRsets(nil, curr)
+			if len(changes) != 2 || changes[0].After.ObjectType != "comment" || changes[1].After.ObjectType != "record" {
+				t.Fatalf("expected the comment before the record, got %v", changes)
+			}
+		}
+	})
+
+	t.Run("initial result set", func(t *testing.T) {
+		changes := DiffRRsets(nil, base)
+		if len(changes) != 2 {
+			t.Fatalf("expected 2 added RRsets, got %v", changes)
+		}
+		for _, c := range changes {
+			if c.Kind != ChangeAdded {
+				t.Errorf("expected added, got %s", c.Kind)
+			}
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package misc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+// RRset is a set of search results sharing zone, name, type and object type.
+// Comments are the comments of its first record.
+type RRset struct {
+	Zone       string         `json:"zone"`
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Ttl        int            `json:"ttl"`
+	ObjectType string         `json:"object_type"`
+	Contents   []string       `json:"contents"`
+	Comments   []pdns.Comment `json:"comments,omitempty"`
+}
+
+func rrsetKey(r pdns.PDNSSearchResponseItem) string {
+	return fmt.Sprintf("%s|%s|%s|%s", r.Zone, r.Name, r.Type, r.ObjectType)
+}
+
+// GroupRRsets folds the records into RRsets. The RRsets are returned in the
+// order their first record appears in.
+func GroupRRsets(records []pdns.PDNSSearchResponseItem) []RRset {
+	var rrsets []RRset
+	index := make(map[string]int)
+	for _, r := range records {
+		key := rrsetKey(r)
+		if i, ok := index[key]; ok {
+			rrsets[i].Contents = append(rrsets[i].Contents, r.Content)
+			continue
+		}
+		index[key] = len(rrsets)
+		rrsets = append(rrsets, RRset{
+			Zone:       r.Zone,
+			Name:       r.Name,
+			Type:       r.Type,
+			Ttl:        r.Ttl,
+			ObjectType: r.ObjectType,
+			Contents:   []string{r.Content},
+			Comments:   r.Comments,
+		})
+	}
+	return rrsets
+}
+
+// foldRRsets returns one record per RRset with all contents joined by sep.
+// The disabled records of an RRset are folded into a row of their own, so
+// the rows keep the disabled flag.
+func foldRRsets(records []pdns.PDNSSearchResponseItem, sep string) []pdns.PDNSSearchResponseItem {
+	var folded []pdns.PDNSSearchResponseItem
+	index := make(map[string]int)
+	for _, r := range records {
+		key := fmt.Sprintf("%s|%t", rrsetKey(r), r.Disabled)
+		if i, ok := index[key]; ok {
+			folded[i].Content += sep + r.Content
+			continue
+		}
+		index[key] = len(folded)
+		folded = append(folded, pdns.PDNSSearchResponseItem{
+			Zone:       r.Zone,
+			Name:       r.Name,
+			Type:       r.Type,
+			Ttl:        r.Ttl,
+			ObjectType: r.ObjectType,
+			Content:    r.Content,
+			Disabled:   r.Disabled,
+			Comments:   r.Comments,
+		})
+	}
+	return folded
+}
+
+type section struct {
+	title   string
+	records []pdns.PDNSSearchResponseItem
+}
+
+// groupSections splits the records into sections by key. Sections are
+// returned in the order their first record appears in.
+func groupSections(records []pdns.PDNSSearchResponseItem, key func(pdns.PDNSSearchResponseItem) string) []section {
+	var sections []section
+	index := make(map[string]int)
+	for _, r := range records {
+		k := key(r)
+		i, ok := index[k]
+		if !ok {
+			i = len(sections)
+			index[k] = i
+			sections = append(sections, section{title: k})
+		}
+		sections[i].records = append(sections[i].records, r)
+	}
+	return sections
+}
+
+// groupNested nests the records as zone -> name -> type -> records.
+func groupNested(records []pdns.PDNSSearchResponseItem) map[string]map[string]map[string][]pdns.PDNSSearchResponseItem {
+	nested := make(map[string]map[string]map[string][]pdns.PDNSSearchResponseItem)
+	for _, r := range records {
+		names, ok := nested[r.Zone]
+		if !ok {
+			names = make(map[string]map[string][]pdns.PDNSSearchResponseItem)
+			nested[r.Zone] = names
+		}
+		types, ok := names[r.Name]
+		if !ok {
+			types = make(map[string][]pdns.PDNSSearchResponseItem)
+			names[r.Name] = types
+		}
+		types[r.Type] = append(types[r.Type], r)
+	}
+	return nested
+}
+
+// OutputGrouped prints the records grouped by rrset, name or zone. RRsets are
+// folded into a single row per RRset, names and zones become section
+// headers with the number of records in each section. JSON output is always
+// nested as zone -> name -> type -> records.
+func OutputGrouped(records []pdns.PDNSSearchResponseItem, groupBy, format, delimiter string) error {
+	var key func(pdns.PDNSSearchResponseItem) string
+	switch groupBy {
+	case "rrset":
+	case "name":
+		key = func(r pdns.PDNSSearchResponseItem) string { return r.Name }
+	case "zone":
+		key = func(r pdns.PDNSSearchResponseItem) string { return r.Zone }
+	default:
+		return fmt.Errorf("invalid group-by: %s (valid options: rrset, name, zone)", groupBy)
+	}
+
+	if format == "json" {
+		output, err := json.MarshalIndent(groupNested(records), "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling to JSON: %w", err)
+		}
+		fmt.Println(string(output))
+		return nil
+	}
+
+	if key == nil {
+		switch format {
+		case "table":
+			OutputToTable(foldRRsets(records, ", "))
+		case "raw":
+			OutputToStdout(foldRRsets(records, ","))
+		case "csv":
+			sep := ","
+			if delimiter == sep {
+				sep = "|"
+			}
+			OutputToCSV(foldRRsets(records, sep), delimiter)
+		default:
+			return fmt.Errorf("group-by rrset is not supported for %s output", format)
+		}
+		return nil
+	}
+
+	if format != "table" && format != "raw" {
+		return fmt.Errorf("group-by %s is not supported for %s output", groupBy, format)
+	}
+	for i, s := range groupSections(records, key) {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(headerColor.Sprintf("%s (%d)", s.title, len(s.records)))
+		if format == "table" {
+			OutputToTable(s.records)
+		} else {
+			fmt.Print(generateOutput(s.records, DefaultDelimiter))
+		}
+	}
+	return nil
+}
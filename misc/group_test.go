@@ -0,0 +1,89 @@
+package misc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+var groupRecords = []pdns.PDNSSearchResponseItem{
+	{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "10.0.0.1", Ttl: 300, ObjectType: "record"},
+	{Zone: "example.com.", Name: "fw.example.com.", Type: "AAAA", Content: "2001:db8::1", Ttl: 300, ObjectType: "record"},
+	{Zone: "test.com.", Name: "fw.test.com.", Type: "A", Content: "10.1.0.1", Ttl: 60, ObjectType: "record"},
+	{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "10.0.0.2", Ttl: 300, ObjectType: "record"},
+}
+
+func TestGroupRRsets(t *testing.T) {
+	rrsets := GroupRRsets(groupRecords)
+	if len(rrsets) != 3 {
+		t.Fatalf("expected 3 RRsets, got %d: %v", len(rrsets), rrsets)
+	}
+	if rrsets[0].Type != "A" || !reflect.DeepEqual(rrsets[0].Contents, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("expected folded A RRset, got %v", rrsets[0])
+	}
+	if rrsets[1].Type != "AAAA" || rrsets[2].Zone != "test.com." {
+		t.Errorf("expected order of first appearance, got %v", rrsets)
+	}
+}
+
+func TestFoldRRsets(t *testing.T) {
+	folded := foldRRsets(groupRecords, ", ")
+	if len(folded) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(folded))
+	}
+	if folded[0].Content != "10.0.0.1, 10.0.0.2" {
+		t.Errorf("expected joined content, got %q", folded[0].Content)
+	}
+}
+
+func TestFoldRRsetsDisabled(t *testing.T) {
+	records := []pdns.PDNSSearchResponseItem{
+		{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "10.0.0.1", Ttl: 300, ObjectType: "record"},
+		{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "10.0.0.2", Ttl: 300, ObjectType: "record", Disabled: true},
+		{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "10.0.0.3", Ttl: 300, ObjectType: "record"},
+	}
+	folded := foldRRsets(records, ", ")
+	if len(folded) != 2 {
+		t.Fatalf("expected 2 rows, got %d: %v", len(folded), folded)
+	}
+	if folded[0].Content != "10.0.0.1, 10.0.0.3" || folded[0].Disabled {
+		t.Errorf("expected enabled row, got %+v", folded[0])
+	}
+	if folded[1].Content != "10.0.0.2" || !folded[1].Disabled {
+		t.Errorf("expected disabled row, got %+v", folded[1])
+	}
+}
+
+func TestGroupSections(t *testing.T) {
+	sections := groupSections(groupRecords, func(r pdns.PDNSSearchResponseItem) string { return r.Zone })
+	if len(sections) != 2 {
+		t.Fatalf("expected 2 sections, got %d", len(sections))
+	}
+	if sections[0].title != "example.com." || len(sections[0].records) != 3 {
+		t.Errorf("expected 3 records in example.com., got %v", sections[0])
+	}
+	if sections[1].title != "test.com." || len(sections[1].records) != 1 {
+		t.Errorf("expected 1 record in test.com., got %v", sections[1])
+	}
+}
+
+func TestGroupNested(t *testing.T) {
+	nested := groupNested(groupRecords)
+	a := nested["example.com."]["fw.example.com."]["A"]
+	if len(a) != 2 {
+		t.Errorf("expected 2 A records, got %v", a)
+	}
+	if len(nested["test.com."]["fw.test.com."]["A"]) != 1 {
+		t.Errorf("expected 1 record in test.com., got %v", nested["test.com."])
+	}
+}
+
+func TestOutputGroupedInvalid(t *testing.T) {
+	if err := OutputGrouped(groupRecords, "type", "table", ";"); err == nil {
+		t.Error("expected error for invalid group-by")
+	}
+	if err := OutputGrouped(groupRecords, "zone", "csv", ";"); err == nil {
+		t.Error("expected error for sections in csv output")
+	}
+}
@@ -0,0 +1,110 @@
+package misc
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// highlightFragments holds the lowercased literal parts of the search
+// patterns. Matches of these fragments are highlighted in table output.
+var highlightFragments []string
+
+// SetHighlightPatterns sets the search patterns whose matches get highlighted
+// in table output. The wildcards * and ? are dropped, every literal fragment
+// in between is highlighted on its own. Passing no patterns turns
+// highlighting off.
+func SetHighlightPatterns(patterns []string) {
+	highlightFragments = nil
+	for _, p := range patterns {
+		fragments := strings.FieldsFunc(p, func(r rune) bool {
+			return r == '*' || r == '?'
+		})
+		for _, f := range fragments {
+			highlightFragments = append(highlightFragments, asciiLower(f))
+		}
+	}
+}
+
+// asciiLower lowercases only ASCII letters so byte offsets in the result
+// stay valid for the input.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
+
+// matchRanges returns the sorted and merged byte ranges of s that match any
+// of the fragments, ignoring case.
+func matchRanges(s string, fragments []string) [][2]int {
+	if len(fragments) == 0 || s == "" {
+		return nil
+	}
+	lower := asciiLower(s)
+	marked := make([]bool, len(s))
+	for _, f := range fragments {
+		if f == "" {
+			continue
+		}
+		for offset := 0; offset < len(lower); {
+			i := strings.Index(lower[offset:], f)
+			if i < 0 {
+				break
+			}
+			start := offset + i
+			for j := start; j < start+len(f); j++ {
+				marked[j] = true
+			}
+			offset = start + 1
+		}
+	}
+
+	var ranges [][2]int
+	for i := 0; i < len(marked); i++ {
+		if !marked[i] {
+			continue
+		}
+		start := i
+		for i < len(marked) && marked[i] {
+			i++
+		}
+		ranges = append(ranges, [2]int{start, i})
+	}
+	return ranges
+}
+
+// highlight colors s with base and marks the parts matching the search
+// patterns with highlightColor.
+func highlight(s string, base *color.Color) string {
+	ranges := matchRanges(s, highlightFragments)
+	if len(ranges) == 0 {
+		return base.Sprint(s)
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, r := range ranges {
+		if r[0] > last {
+			out.WriteString(base.Sprint(s[last:r[0]]))
+		}
+		out.WriteString(highlightColor.Sprint(s[r[0]:r[1]]))
+		last = r[1]
+	}
+	if last < len(s) {
+		out.WriteString(base.Sprint(s[last:]))
+	}
+	return out.String()
+}
+
+// padRight pads an already colored string to width, based on the length of
+// the uncolored text.
+func padRight(colored string, textLen, width int) string {
+	if textLen >= width {
+		return colored
+	}
+	return colored + strings.Repeat(" ", width-textLen)
+}
@@ -0,0 +1,72 @@
+package misc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestSetHighlightPatterns(t *testing.T) {
+	SetHighlightPatterns([]string{"*fw*", "Ns?.example", "*"})
+	defer SetHighlightPatterns(nil)
+
+	expected := []string{"fw", "ns", ".example"}
+	if !reflect.DeepEqual(highlightFragments, expected) {
+		t.Errorf("expected fragments %v, got %v", expected, highlightFragments)
+	}
+}
+
+func TestMatchRanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		fragments []string
+		expected  [][2]int
+	}{
+		{"no fragments", "fw.example.com.", nil, nil},
+		{"single match", "fw.example.com.", []string{"fw"}, [][2]int{{0, 2}}},
+		{"case insensitive", "FW-1.example.com.", []string{"fw"}, [][2]int{{0, 2}}},
+		{"multiple matches", "fw-fw", []string{"fw"}, [][2]int{{0, 2}, {3, 5}}},
+		{"overlapping fragments merged", "firewall", []string{"fire", "rewall"}, [][2]int{{0, 8}}},
+		{"no match", "ns1.example.com.", []string{"fw"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := matchRanges(tt.input, tt.fragments)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("matchRanges(%q, %v) = %v, want %v", tt.input, tt.fragments, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = oldNoColor }()
+
+	SetHighlightPatterns([]string{"*fw*"})
+	defer SetHighlightPatterns(nil)
+
+	base := color.New(color.FgGreen)
+	out := highlight("a-fw-b", base)
+
+	if !strings.Contains(out, highlightColor.Sprint("fw")) {
+		t.Errorf("expected highlighted fragment in %q", out)
+	}
+	if !strings.HasPrefix(out, base.Sprint("a-")) || !strings.HasSuffix(out, base.Sprint("-b")) {
+		t.Errorf("expected base color around the match, got %q", out)
+	}
+}
+
+func TestPadRight(t *testing.T) {
+	if got := padRight("\x1b[1mab\x1b[0m", 2, 5); got != "\x1b[1mab\x1b[0m   " {
+		t.Errorf("unexpected padding: %q", got)
+	}
+	if got := padRight("abcdef", 6, 3); got != "abcdef" {
+		t.Errorf("expected no padding, got %q", got)
+	}
+}
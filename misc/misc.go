@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"sort"
+	"slices"
 	"strconv"
 	"strings"
 	"text/tabwriter"
@@ -22,7 +22,7 @@ const (
 
 var headers = []string{"Zone", "Name", "Type", "Content", "TTL", "Object Type"}
 
-// Color settings
+// Color settings, defaults of the dark theme. See ApplyTheme.
 var (
 	headerColor  = color.New(color.FgHiWhite, color.Bold)
 	zoneColor    = color.New(color.FgCyan)
@@ -33,11 +33,16 @@ var (
 	objectColor  = color.New(color.FgBlue)
 	addColor     = color.New(color.FgGreen)
 	removeColor  = color.New(color.FgRed)
+	modifyColor  = color.New(color.FgYellow)
+
+	highlightColor = color.New(color.Bold, color.ReverseVideo)
+	disabledColor  = color.New(color.FgHiBlack, color.CrossedOut)
 )
 
-// Helper function to format record as string (for non-colored output)
+// Helper function to format record as string (for non-colored output),
+// disabled records are marked in the object type
 func formatRecord(record pdns.PDNSSearchResponseItem, delimiter string) string {
-	return fmt.Sprintf("%s%s%s%s%s%s%s%s%d%s%s", record.Zone, delimiter, record.Name, delimiter, record.Type, delimiter, record.Content, delimiter, record.Ttl, delimiter, record.ObjectType)
+	return fmt.Sprintf("%s%s%s%s%s%s%s%s%d%s%s", record.Zone, delimiter, record.Name, delimiter, record.Type, delimiter, record.Content, delimiter, record.Ttl, delimiter, objectLabel(record))
 }
 
 func generateOutput(records []pdns.PDNSSearchResponseItem, delimiter string) string {
@@ -55,15 +60,53 @@ func OutputToStdout(records []pdns.PDNSSearchResponseItem) {
 	fmt.Print(generateOutput(records, DefaultDelimiter))
 }
 
+// disabledMarker is appended to the object type of disabled records in
+// text output.
+const disabledMarker = " [disabled]"
+
+func objectLabel(r pdns.PDNSSearchResponseItem) string {
+	if r.Disabled {
+		return r.ObjectType + disabledMarker
+	}
+	return r.ObjectType
+}
+
+// formatComments joins the comments of a record with their accounts.
+func formatComments(comments []pdns.Comment) string {
+	parts := make([]string, len(comments))
+	for i, c := range comments {
+		parts[i] = c.Content
+		if c.Account != "" {
+			parts[i] += " (" + c.Account + ")"
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func hasComments(r pdns.PDNSSearchResponseItem) bool {
+	return len(r.Comments) > 0
+}
+
 func OutputToTable(records []pdns.PDNSSearchResponseItem) {
+	// the comment column is only shown if details were added
+	showComments := slices.ContainsFunc(records, hasComments)
+	tableHeaders := headers
+	if showComments {
+		tableHeaders = append(slices.Clip(headers), "Comment")
+	}
+
 	if color.NoColor {
 		writer := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
 		defer writer.Flush()
 		if !viper.GetBool("no-header") {
-			fmt.Fprintln(writer, strings.Join(headers, TabDelimiter))
+			fmt.Fprintln(writer, strings.Join(tableHeaders, TabDelimiter))
 		}
 		for _, r := range records {
-			fmt.Fprintln(writer, formatRecord(r, TabDelimiter))
+			line := formatRecord(r, TabDelimiter)
+			if showComments {
+				line += TabDelimiter + formatComments(r.Comments)
+			}
+			fmt.Fprintln(writer, line)
 		}
 		return
 	}
@@ -75,6 +118,7 @@ func OutputToTable(records []pdns.PDNSSearchResponseItem) {
 	typeWidth := len(headers[2])
 	contentWidth := len(headers[3])
 	ttlWidth := len(headers[4])
+	objectWidth := len(headers[5])
 
 	for _, r := range records {
 		if len(r.Zone) > zoneWidth {
@@ -93,6 +137,7 @@ func OutputToTable(records []pdns.PDNSSearchResponseItem) {
 		if len(ttlStr) > ttlWidth {
 			ttlWidth = len(ttlStr)
 		}
+		objectWidth = max(objectWidth, len(objectLabel(r)))
 	}
 
 	// Add some padding
@@ -101,92 +146,41 @@ func OutputToTable(records []pdns.PDNSSearchResponseItem) {
 	typeWidth += 2
 	contentWidth += 2
 	ttlWidth += 2
+	objectWidth += 2
 
 	// Print headers
 	if !viper.GetBool("no-header") {
+		last := headerColor.Sprint(headers[5])
+		if showComments {
+			last = headerColor.Sprintf("%-*s", objectWidth, headers[5]) + headerColor.Sprint(tableHeaders[6])
+		}
 		fmt.Printf("%s%s%s%s%s%s\n",
 			headerColor.Sprintf("%-*s", zoneWidth, headers[0]),
 			headerColor.Sprintf("%-*s", nameWidth, headers[1]),
 			headerColor.Sprintf("%-*s", typeWidth, headers[2]),
 			headerColor.Sprintf("%-*s", contentWidth, headers[3]),
 			headerColor.Sprintf("%-*s", ttlWidth, headers[4]),
-			headerColor.Sprint(headers[5]))
+			last)
 	}
 
-	// Print records with fixed width columns
+	// Print records with fixed width columns, disabled records in a single
+	// color
 	for _, r := range records {
+		zc, nc, tc, cc, ttlc, oc := zoneColor, nameColor, typeColor, contentColor, ttlColor, objectColor
+		if r.Disabled {
+			zc, nc, tc, cc, ttlc, oc = disabledColor, disabledColor, disabledColor, disabledColor, disabledColor, disabledColor
+		}
+		last := oc.Sprint(objectLabel(r))
+		if showComments {
+			last = oc.Sprintf("%-*s", objectWidth, objectLabel(r)) + contentColor.Sprint(formatComments(r.Comments))
+		}
 		fmt.Printf("%s%s%s%s%s%s\n",
-			zoneColor.Sprintf("%-*s", zoneWidth, r.Zone),
-			nameColor.Sprintf("%-*s", nameWidth, r.Name),
-			typeColor.Sprintf("%-*s", typeWidth, r.Type),
-			contentColor.Sprintf("%-*s", contentWidth, r.Content),
-			ttlColor.Sprintf("%-*d", ttlWidth, r.Ttl),
-			objectColor.Sprint(r.ObjectType))
-	}
-}
-
-func SortRecords(records []pdns.PDNSSearchResponseItem, sortBy string) error {
-	switch sortBy {
-	case "name":
-		sort.Slice(records, func(i, j int) bool {
-			return records[i].Name < records[j].Name
-		})
-	case "zone":
-		sort.Slice(records, func(i, j int) bool {
-			if records[i].Zone == records[j].Zone {
-				return records[i].Name < records[j].Name
-			}
-			return records[i].Zone < records[j].Zone
-		})
-	case "ttl":
-		sort.Slice(records, func(i, j int) bool {
-			if records[i].Ttl == records[j].Ttl {
-				return records[i].Name < records[j].Name
-			}
-			return records[i].Ttl < records[j].Ttl
-		})
-	case "type":
-		sort.Slice(records, func(i, j int) bool {
-			if records[i].Type == records[j].Type {
-				return records[i].Name < records[j].Name
-			}
-			return records[i].Type < records[j].Type
-		})
-	default:
-		return fmt.Errorf("invalid sort field: %s (valid options: name, zone, ttl, type)", sortBy)
-	}
-	return nil
-}
-
-func OutputStats(records []pdns.PDNSSearchResponseItem) {
-	typeCount := make(map[string]int)
-	zoneCount := make(map[string]int)
-
-	for _, r := range records {
-		typeCount[r.Type]++
-		zoneCount[r.Zone]++
-	}
-
-	fmt.Printf("Total Records: %d\n\n", len(records))
-
-	fmt.Println("By Type:")
-	types := make([]string, 0, len(typeCount))
-	for t := range typeCount {
-		types = append(types, t)
-	}
-	sort.Strings(types)
-	for _, t := range types {
-		fmt.Printf("  %-6s %d\n", t, typeCount[t])
-	}
-
-	fmt.Printf("\nBy Zone:\n")
-	zones := make([]string, 0, len(zoneCount))
-	for z := range zoneCount {
-		zones = append(zones, z)
-	}
-	sort.Strings(zones)
-	for _, z := range zones {
-		fmt.Printf("  %-30s %d\n", z, zoneCount[z])
+			padRight(highlight(r.Zone, zc), len(r.Zone), zoneWidth),
+			padRight(highlight(r.Name, nc), len(r.Name), nameWidth),
+			tc.Sprintf("%-*s", typeWidth, r.Type),
+			padRight(highlight(r.Content, cc), len(r.Content), contentWidth),
+			ttlc.Sprintf("%-*d", ttlWidth, r.Ttl),
+			last)
 	}
 }
 
@@ -202,45 +196,3 @@ func OutputToJSON(records []pdns.PDNSSearchResponseItem) {
 	}
 	fmt.Println(string(output))
 }
-
-func RecordsEqual(a, b []pdns.PDNSSearchResponseItem) bool {
-	added, removed := DiffRecords(a, b)
-	return len(added) == 0 && len(removed) == 0
-}
-
-func recordKey(r pdns.PDNSSearchResponseItem) string {
-	return fmt.Sprintf("%s|%s|%s|%s|%d", r.Zone, r.Name, r.Type, r.Content, r.Ttl)
-}
-
-// DiffRecords returns records added and removed between prev and curr.
-func DiffRecords(prev, curr []pdns.PDNSSearchResponseItem) (added, removed []pdns.PDNSSearchResponseItem) {
-	prevSet := make(map[string]struct{}, len(prev))
-	for _, r := range prev {
-		prevSet[recordKey(r)] = struct{}{}
-	}
-	currSet := make(map[string]struct{}, len(curr))
-	for _, r := range curr {
-		currSet[recordKey(r)] = struct{}{}
-	}
-	for _, r := range curr {
-		if _, ok := prevSet[recordKey(r)]; !ok {
-			added = append(added, r)
-		}
-	}
-	for _, r := range prev {
-		if _, ok := currSet[recordKey(r)]; !ok {
-			removed = append(removed, r)
-		}
-	}
-	return
-}
-
-// OutputDiff prints removed records in red and added records in green.
-func OutputDiff(added, removed []pdns.PDNSSearchResponseItem) {
-	for _, r := range removed {
-		removeColor.Printf("- %s\n", formatRecord(r, SpaceDelimiter))
-	}
-	for _, r := range added {
-		addColor.Printf("+ %s\n", formatRecord(r, SpaceDelimiter))
-	}
-}
@@ -0,0 +1,91 @@
+package misc
+
+import "strings"
+
+// isWordChar reports whether c continues a name or address, so a match
+// next to it is only part of a longer word.
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c == '-' || c == '_'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// joinsGroup reports whether the ':' at s[i] follows a group of an IPv6
+// address, so a match after it is only part of the address. The "ip6:" of
+// an SPF record is no group, it ends in a word.
+func joinsGroup(s string, i int) bool {
+	j := i
+	for j > 0 && isHexDigit(s[j-1]) {
+		j--
+	}
+	if j == i {
+		return j > 0 && s[j-1] == ':'
+	}
+	return j == 0 || !isWordChar(s[j-1])
+}
+
+// atBoundary reports whether the match s[start:end] is a whole word: it is
+// neither preceded by a word character or a dot, nor followed by a word
+// character or a dot that starts another label. A ':' next to a group of
+// an IPv6 address continues the address, unless it follows an IPv4 address
+// as the separator of a port.
+func atBoundary(s string, start, end int) bool {
+	if start > 0 {
+		c := s[start-1]
+		if isWordChar(c) || c == '.' || c == ':' && joinsGroup(s, start-1) {
+			return false
+		}
+	}
+	if end < len(s) {
+		if isWordChar(s[end]) {
+			return false
+		}
+		if s[end] == '.' && end+1 < len(s) && isWordChar(s[end+1]) {
+			return false
+		}
+		if s[end] == ':' && end+1 < len(s) && (isHexDigit(s[end+1]) || s[end+1] == ':') && !strings.Contains(s[start:end], ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// ReplaceBounded replaces all whole word occurrences of old in s with new.
+// "10.0.0.1" is replaced in "ip4:10.0.0.1/32", but not in "10.0.0.10" or
+// "10.10.0.0.1", and "2001:db8::1" not in "2001:db8::1:5". With ignoreCase,
+// as for names, the case of old and s is ignored. It returns the new string
+// and the number of replacements.
+func ReplaceBounded(s, old, new string, ignoreCase bool) (string, int) {
+	if old == "" {
+		return s, 0
+	}
+	lower, lowerOld := s, old
+	if ignoreCase {
+		lower, lowerOld = asciiLower(s), asciiLower(old)
+	}
+
+	var result strings.Builder
+	count, last := 0, 0
+	for i := 0; i+len(old) <= len(s); {
+		idx := strings.Index(lower[i:], lowerOld)
+		if idx < 0 {
+			break
+		}
+		start, end := i+idx, i+idx+len(old)
+		if !atBoundary(s, start, end) {
+			i = start + 1
+			continue
+		}
+		result.WriteString(s[last:start])
+		result.WriteString(new)
+		last, i = end, end
+		count++
+	}
+	if count == 0 {
+		return s, 0
+	}
+	result.WriteString(s[last:])
+	return result.String(), count
+}
@@ -0,0 +1,41 @@
+package misc
+
+import "testing"
+
+func TestReplaceBounded(t *testing.T) {
+	tests := []struct {
+		s, old, new string
+		ignoreCase  bool
+		expected    string
+		count       int
+	}{
+		{"10.0.0.1", "10.0.0.1", "10.1.0.1", true, "10.1.0.1", 1},
+		{"10.0.0.10", "10.0.0.1", "10.1.0.1", true, "10.0.0.10", 0},
+		{"110.0.0.1", "10.0.0.1", "10.1.0.1", true, "110.0.0.1", 0},
+		{"10.10.0.0.1", "0.0.1", "0.0.2", true, "10.10.0.0.1", 0},
+		{`"v=spf1 ip4:10.0.0.1/32 ip4:10.0.0.1 -all"`, "10.0.0.1", "10.1.0.1", true, `"v=spf1 ip4:10.1.0.1/32 ip4:10.1.0.1 -all"`, 2},
+		{"fw-1.example.com.", "fw-1.example.com.", "fw-2.example.com.", true, "fw-2.example.com.", 1},
+		{"fw-1.example.com.au.", "fw-1.example.com.", "fw-2.example.com.", true, "fw-1.example.com.au.", 0},
+		{"10 mail.example.com.", "mail.example.com", "mx.example.com", true, "10 mx.example.com.", 1},
+		{"ns1.example.com.", "example.com.", "example.org.", true, "ns1.example.com.", 0},
+		{"FW-1.Example.com.", "fw-1.example.com.", "fw-2.example.com.", true, "fw-2.example.com.", 1},
+		{"anything", "", "x", true, "anything", 0},
+		{"2001:db8::1", "2001:db8::1", "2001:db8::2", false, "2001:db8::2", 1},
+		{"2001:db8::1:5", "2001:db8::1", "2001:db8::2", false, "2001:db8::1:5", 0},
+		{"2001:db8::10", "2001:db8::1", "2001:db8::2", false, "2001:db8::10", 0},
+		{"2001:db8::1", "db8::1", "db8::2", false, "2001:db8::1", 0},
+		{"::1", "1", "2", false, "::1", 0},
+		{`"v=spf1 ip6:2001:db8::1 -all"`, "2001:db8::1", "2001:db8::2", false, `"v=spf1 ip6:2001:db8::2 -all"`, 1},
+		{"10.0.0.1:53", "10.0.0.1", "10.1.0.1", false, "10.1.0.1:53", 1},
+		{`"Owner: Alice"`, "alice", "bob", false, `"Owner: Alice"`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s+"/"+tt.old, func(t *testing.T) {
+			got, count := ReplaceBounded(tt.s, tt.old, tt.new, tt.ignoreCase)
+			if got != tt.expected || count != tt.count {
+				t.Errorf("ReplaceBounded(%q, %q, %q) = %q, %d, want %q, %d", tt.s, tt.old, tt.new, got, count, tt.expected, tt.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package misc
+
+import (
+	"cmp"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+var sortFields = map[string]func(a, b pdns.PDNSSearchResponseItem) int{
+	"name": func(a, b pdns.PDNSSearchResponseItem) int {
+		return CompareDNSNames(a.Name, b.Name)
+	},
+	"zone": func(a, b pdns.PDNSSearchResponseItem) int {
+		return CompareDNSNames(a.Zone, b.Zone)
+	},
+	"ttl": func(a, b pdns.PDNSSearchResponseItem) int {
+		return cmp.Compare(a.Ttl, b.Ttl)
+	},
+	"type": func(a, b pdns.PDNSSearchResponseItem) int {
+		return strings.Compare(a.Type, b.Type)
+	},
+	"content": func(a, b pdns.PDNSSearchResponseItem) int {
+		return CompareContent(a.Content, b.Content)
+	},
+}
+
+type sortKey[T any] struct {
+	field   string
+	compare func(a, b T) int
+	desc    bool
+}
+
+// parseSortKeys parses a comma separated list of sort fields. A leading "-"
+// sorts the field in descending order. valid lists the fields for the error
+// message.
+func parseSortKeys[T any](sortBy string, fields map[string]func(a, b T) int, valid string) ([]sortKey[T], error) {
+	var keys []sortKey[T]
+	for field := range strings.SplitSeq(sortBy, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		desc := false
+		if rest, ok := strings.CutPrefix(field, "-"); ok {
+			field = rest
+			desc = true
+		}
+		compare, ok := fields[field]
+		if !ok {
+			return nil, fmt.Errorf("invalid sort field: %s (valid options: %s)", field, valid)
+		}
+		keys = append(keys, sortKey[T]{field: field, compare: compare, desc: desc})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no sort field given")
+	}
+	return keys, nil
+}
+
+// sortByKeys sorts items stable by keys. Items equal in all keys are ordered
+// by the tiebreak field, unless it is already one of the keys.
+func sortByKeys[T any](items []T, keys []sortKey[T], fields map[string]func(a, b T) int, tiebreak string) {
+	if !slices.ContainsFunc(keys, func(k sortKey[T]) bool { return k.field == tiebreak }) {
+		keys = append(keys, sortKey[T]{field: tiebreak, compare: fields[tiebreak]})
+	}
+
+	slices.SortStableFunc(items, func(a, b T) int {
+		for _, k := range keys {
+			c := k.compare(a, b)
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// SortRecords sorts the records by a comma separated list of fields, e.g.
+// "zone,type,-ttl". Fields prefixed with "-" are sorted descending. Records
+// equal in all fields are ordered by name. The sort is stable.
+func SortRecords(records []pdns.PDNSSearchResponseItem, sortBy string) error {
+	keys, err := parseSortKeys(sortBy, sortFields, "name, zone, ttl, type, content")
+	if err != nil {
+		return err
+	}
+	sortByKeys(records, keys, sortFields, "name")
+	return nil
+}
+
+// CompareDNSNames compares two DNS names in hierarchical order, label by
+// label from right to left. A parent sorts before its children.
+func CompareDNSNames(a, b string) int {
+	aLabels := strings.Split(strings.TrimSuffix(asciiLower(a), "."), ".")
+	bLabels := strings.Split(strings.TrimSuffix(asciiLower(b), "."), ".")
+	for i, j := len(aLabels)-1, len(bLabels)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := CompareNatural(aLabels[i], bLabels[j]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(aLabels), len(bLabels))
+}
+
+// CompareContent compares record contents. IP addresses sort numerically
+// and before any other content, IPv4 before IPv6. Everything else is
+// compared in natural order.
+func CompareContent(a, b string) int {
+	aIP, aErr := netip.ParseAddr(a)
+	bIP, bErr := netip.ParseAddr(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return aIP.Compare(bIP)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return CompareNatural(a, b)
+}
+
+// CompareNatural compares two strings treating runs of digits as numbers,
+// so "fw-2" sorts before "fw-10".
+func CompareNatural(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			si, sj := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			numA := strings.TrimLeft(a[si:i], "0")
+			numB := strings.TrimLeft(b[sj:j], "0")
+			if c := cmp.Compare(len(numA), len(numB)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(numA, numB); c != 0 {
+				return c
+			}
+			continue
+		}
+		if c := cmp.Compare(a[i], b[j]); c != 0 {
+			return c
+		}
+		i++
+		j++
+	}
+	if c := cmp.Compare(len(a)-i, len(b)-j); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
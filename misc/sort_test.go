@@ -0,0 +1,171 @@
+package misc
+
+import (
+	"testing"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+func TestSortRecords(t *testing.T) {
+	records := []pdns.PDNSSearchResponseItem{
+		{Name: "c.example.com.", Type: "A", Zone: "example.com.", Ttl: 300},
+		{Name: "a.example.com.", Type: "AAAA", Zone: "example.com.", Ttl: 3600},
+		{Name: "b.test.com.", Type: "A", Zone: "test.com.", Ttl: 300},
+	}
+
+	t.Run("sort by name", func(t *testing.T) {
+		r := make([]pdns.PDNSSearchResponseItem, len(records))
+		copy(r, records)
+		err := SortRecords(r, "name")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if r[0].Name != "a.example.com." || r[1].Name != "c.example.com." || r[2].Name != "b.test.com." {
+			t.Errorf("expected sorted by name in DNS order, got %v", r)
+		}
+	})
+
+	t.Run("sort by zone", func(t *testing.T) {
+		r := make([]pdns.PDNSSearchResponseItem, len(records))
+		copy(r, records)
+		err := SortRecords(r, "zone")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if r[0].Zone != "example.com." || r[2].Zone != "test.com." {
+			t.Errorf("expected sorted by zone, got %v", r)
+		}
+	})
+
+	t.Run("sort by ttl", func(t *testing.T) {
+		r := make([]pdns.PDNSSearchResponseItem, len(records))
+		copy(r, records)
+		err := SortRecords(r, "ttl")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if r[0].Ttl != 300 || r[2].Ttl != 3600 {
+			t.Errorf("expected sorted by ttl, got %v", r)
+		}
+	})
+
+	t.Run("sort by type", func(t *testing.T) {
+		r := make([]pdns.PDNSSearchResponseItem, len(records))
+		copy(r, records)
+		err := SortRecords(r, "type")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if r[0].Type != "A" || r[2].Type != "AAAA" {
+			t.Errorf("expected sorted by type, got %v", r)
+		}
+	})
+
+	t.Run("invalid sort field", func(t *testing.T) {
+		r := make([]pdns.PDNSSearchResponseItem, len(records))
+		copy(r, records)
+		err := SortRecords(r, "invalid")
+		if err == nil {
+			t.Error("expected error for invalid sort field")
+		}
+	})
+}
+
+func TestSortRecordsMultipleKeys(t *testing.T) {
+	records := []pdns.PDNSSearchResponseItem{
+		{Name: "b.example.com.", Type: "A", Zone: "example.com.", Ttl: 300},
+		{Name: "a.example.com.", Type: "A", Zone: "example.com.", Ttl: 3600},
+		{Name: "c.example.com.", Type: "AAAA", Zone: "example.com.", Ttl: 60},
+		{Name: "a.test.com.", Type: "A", Zone: "test.com.", Ttl: 300},
+	}
+
+	err := SortRecords(records, "zone,type,-ttl")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"a.example.com.", "b.example.com.", "c.example.com.", "a.test.com."}
+	for i, name := range expected {
+		if records[i].Name != name {
+			t.Errorf("position %d: expected %s, got %s", i, name, records[i].Name)
+		}
+	}
+}
+
+func TestSortRecordsContent(t *testing.T) {
+	records := []pdns.PDNSSearchResponseItem{
+		{Name: "a.", Content: "target.example.com."},
+		{Name: "b.", Content: "10.0.0.10"},
+		{Name: "c.", Content: "2001:db8::1"},
+		{Name: "d.", Content: "10.0.0.2"},
+	}
+
+	if err := SortRecords(records, "content"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"10.0.0.2", "10.0.0.10", "2001:db8::1", "target.example.com."}
+	for i, content := range expected {
+		if records[i].Content != content {
+			t.Errorf("position %d: expected %s, got %s", i, content, records[i].Content)
+		}
+	}
+}
+
+func TestSortRecordsStable(t *testing.T) {
+	records := []pdns.PDNSSearchResponseItem{
+		{Name: "a.example.com.", Type: "A", Content: "second"},
+		{Name: "a.example.com.", Type: "A", Content: "first"},
+	}
+
+	if err := SortRecords(records, "type"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if records[0].Content != "second" || records[1].Content != "first" {
+		t.Errorf("expected original order for equal records, got %v", records)
+	}
+}
+
+func TestCompareDNSNames(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"example.com.", "example.com.", 0},
+		{"example.com.", "fw.example.com.", -1},
+		{"b.example.com.", "a.test.com.", -1},
+		{"fw-2.example.com.", "fw-10.example.com.", -1},
+		{"FW.example.com.", "fw.example.com", 0},
+		{"example.org.", "example.com.", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			if result := CompareDNSNames(tt.a, tt.b); result != tt.expected {
+				t.Errorf("CompareDNSNames(%q, %q) = %d, want %d", tt.a, tt.b, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompareNatural(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"fw-2", "fw-10", -1},
+		{"fw-10", "fw-2", 1},
+		{"fw-02", "fw-2", -1},
+		{"abc", "abd", -1},
+		{"abc", "abc", 0},
+		{"ab", "abc", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			if result := CompareNatural(tt.a, tt.b); result != tt.expected {
+				t.Errorf("CompareNatural(%q, %q) = %d, want %d", tt.a, tt.b, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package misc
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/spf13/viper"
+)
+
+// defaultTop limits the top names and content reuse lists if no limit is
+// given.
+const defaultTop = 10
+
+var ttlBuckets = []struct {
+	label string
+	max   int
+}{
+	{"<=60", 60},
+	{"61-300", 300},
+	{"301-3600", 3600},
+	{"3601-86400", 86400},
+	{">86400", math.MaxInt},
+}
+
+// Count is the number of records for a key.
+type Count struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// Stats holds aggregated numbers about a set of records.
+type Stats struct {
+	Total        int     `json:"total"`
+	ByType       []Count `json:"by_type"`
+	ByZone       []Count `json:"by_zone"`
+	ByObjectType []Count `json:"by_object_type"`
+	TTLs         []Count `json:"ttl_distribution"`
+	TopNames     []Count `json:"top_names"`
+	ContentReuse []Count `json:"content_reuse"`
+}
+
+func isRecord(r pdns.PDNSSearchResponseItem) bool {
+	return r.ObjectType == "" || r.ObjectType == "record"
+}
+
+// CountBy counts the records per key.
+func CountBy(records []pdns.PDNSSearchResponseItem, key func(pdns.PDNSSearchResponseItem) string) map[string]int {
+	counts := make(map[string]int)
+	for _, r := range records {
+		counts[key(r)]++
+	}
+	return counts
+}
+
+// sortedByKey returns the counts ordered by key.
+func sortedByKey(counts map[string]int) []Count {
+	result := make([]Count, 0, len(counts))
+	for k, c := range counts {
+		result = append(result, Count{Key: k, Count: c})
+	}
+	slices.SortFunc(result, func(a, b Count) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return result
+}
+
+// topCounts returns the n highest counts, ties ordered by key. n <= 0 returns
+// all counts.
+func topCounts(counts map[string]int, n int) []Count {
+	result := sortedByKey(counts)
+	slices.SortStableFunc(result, func(a, b Count) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+func ttlBucket(ttl int) string {
+	for _, b := range ttlBuckets {
+		if ttl <= b.max {
+			return b.label
+		}
+	}
+	return ttlBuckets[len(ttlBuckets)-1].label
+}
+
+// ComputeStats aggregates the records. top limits the zone list to the
+// largest zones and the top names and content reuse lists, 0 shows all zones
+// and the top 10 names and contents.
+func ComputeStats(records []pdns.PDNSSearchResponseItem, top int) Stats {
+	var plain []pdns.PDNSSearchResponseItem
+	for _, r := range records {
+		if isRecord(r) {
+			plain = append(plain, r)
+		}
+	}
+
+	stats := Stats{
+		Total:        len(records),
+		ByType:       sortedByKey(CountBy(records, func(r pdns.PDNSSearchResponseItem) string { return r.Type })),
+		ByObjectType: sortedByKey(CountBy(records, func(r pdns.PDNSSearchResponseItem) string { return r.ObjectType })),
+	}
+
+	zones := CountBy(records, func(r pdns.PDNSSearchResponseItem) string { return r.Zone })
+	if top > 0 {
+		stats.ByZone = topCounts(zones, top)
+	} else {
+		stats.ByZone = sortedByKey(zones)
+	}
+
+	ttls := CountBy(plain, func(r pdns.PDNSSearchResponseItem) string { return ttlBucket(r.Ttl) })
+	for _, b := range ttlBuckets {
+		stats.TTLs = append(stats.TTLs, Count{Key: b.label, Count: ttls[b.label]})
+	}
+
+	if top <= 0 {
+		top = defaultTop
+	}
+	stats.TopNames = topCounts(CountBy(plain, func(r pdns.PDNSSearchResponseItem) string { return r.Name }), top)
+
+	// count distinct names per content
+	namesPerContent := make(map[string]map[string]struct{})
+	for _, r := range plain {
+		names, ok := namesPerContent[r.Content]
+		if !ok {
+			names = make(map[string]struct{})
+			namesPerContent[r.Content] = names
+		}
+		names[r.Name] = struct{}{}
+	}
+	reuse := make(map[string]int)
+	for content, names := range namesPerContent {
+		if len(names) > 1 {
+			reuse[content] = len(names)
+		}
+	}
+	stats.ContentReuse = topCounts(reuse, top)
+
+	return stats
+}
+
+// OutputStats prints the statistics as table, csv or json.
+func OutputStats(stats Stats, format, delimiter string) error {
+	switch format {
+	case "", "table":
+		outputStatsTable(stats)
+	case "csv":
+		fmt.Print(generateStatsCSV(stats, delimiter))
+	case "json":
+		output, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling to JSON: %w", err)
+		}
+		fmt.Println(string(output))
+	default:
+		return fmt.Errorf("invalid stats format: %s (valid options: table, csv, json)", format)
+	}
+	return nil
+}
+
+func outputStatsTable(stats Stats) {
+	fmt.Printf("Total Records: %d\n\n", stats.Total)
+
+	fmt.Println("By Type:")
+	for _, c := range stats.ByType {
+		fmt.Printf("  %-6s %d\n", c.Key, c.Count)
+	}
+
+	fmt.Printf("\nBy Zone:\n")
+	for _, c := range stats.ByZone {
+		fmt.Printf("  %-30s %d\n", c.Key, c.Count)
+	}
+
+	fmt.Printf("\nBy Object Type:\n")
+	for _, c := range stats.ByObjectType {
+		fmt.Printf("  %-8s %d\n", c.Key, c.Count)
+	}
+
+	fmt.Printf("\nTTL Distribution:\n")
+	largest := 0
+	for _, c := range stats.TTLs {
+		largest = max(largest, c.Count)
+	}
+	for _, c := range stats.TTLs {
+		bar := ""
+		if largest > 0 {
+			bar = strings.Repeat("#", c.Count*40/largest)
+		}
+		fmt.Println(strings.TrimRight(fmt.Sprintf("  %-10s %6d %s", c.Key, c.Count, bar), " "))
+	}
+
+	fmt.Printf("\nTop Names:\n")
+	for _, c := range stats.TopNames {
+		fmt.Printf("  %-40s %d\n", c.Key, c.Count)
+	}
+
+	if len(stats.ContentReuse) > 0 {
+		fmt.Printf("\nContent Reuse (names per content):\n")
+		for _, c := range stats.ContentReuse {
+			fmt.Printf("  %-40s %d\n", c.Key, c.Count)
+		}
+	}
+}
+
+func generateStatsCSV(stats Stats, delimiter string) string {
+	var output strings.Builder
+	if !viper.GetBool("no-header") {
+		output.WriteString(strings.Join([]string{"Section", "Key", "Count"}, delimiter) + "\n")
+	}
+	writeRow := func(section, key string, count int) {
+		output.WriteString(strings.Join([]string{section, key, strconv.Itoa(count)}, delimiter) + "\n")
+	}
+
+	writeRow("total", "", stats.Total)
+	sections := []struct {
+		name   string
+		counts []Count
+	}{
+		{"type", stats.ByType},
+		{"zone", stats.ByZone},
+		{"object_type", stats.ByObjectType},
+		{"ttl", stats.TTLs},
+		{"top_name", stats.TopNames},
+		{"content_reuse", stats.ContentReuse},
+	}
+	for _, s := range sections {
+		for _, c := range s.counts {
+			writeRow(s.name, c.Key, c.Count)
+		}
+	}
+	return output.String()
+}
@@ -0,0 +1,137 @@
+package misc
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+func TestOutputStats(t *testing.T) {
+	records := []pdns.PDNSSearchResponseItem{
+		{Name: "a.example.com.", Type: "A", Zone: "example.com.", Ttl: 300},
+		{Name: "b.example.com.", Type: "A", Zone: "example.com.", Ttl: 300},
+		{Name: "c.example.com.", Type: "AAAA", Zone: "example.com.", Ttl: 3600},
+		{Name: "d.test.com.", Type: "A", Zone: "test.com.", Ttl: 300},
+	}
+
+	// Capture stdout
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	if err := OutputStats(ComputeStats(records, 0), "table", ";"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	output := buf.String()
+
+	t.Run("total count", func(t *testing.T) {
+		if !strings.Contains(output, "Total Records: 4") {
+			t.Errorf("expected total count, got: %s", output)
+		}
+	})
+
+	t.Run("type breakdown", func(t *testing.T) {
+		if !strings.Contains(output, "By Type:") {
+			t.Error("expected type breakdown")
+		}
+		if !strings.Contains(output, "A") || !strings.Contains(output, "AAAA") {
+			t.Error("expected A and AAAA types")
+		}
+	})
+
+	t.Run("zone breakdown", func(t *testing.T) {
+		if !strings.Contains(output, "By Zone:") {
+			t.Error("expected zone breakdown")
+		}
+		if !strings.Contains(output, "example.com.") || !strings.Contains(output, "test.com.") {
+			t.Error("expected example.com and test.com zones")
+		}
+	})
+}
+
+func TestComputeStats(t *testing.T) {
+	records := []pdns.PDNSSearchResponseItem{
+		{Name: "a.example.com.", Type: "A", Zone: "example.com.", Content: "10.0.0.1", Ttl: 60, ObjectType: "record"},
+		{Name: "a.example.com.", Type: "AAAA", Zone: "example.com.", Content: "2001:db8::1", Ttl: 300, ObjectType: "record"},
+		{Name: "b.example.com.", Type: "A", Zone: "example.com.", Content: "10.0.0.1", Ttl: 300, ObjectType: "record"},
+		{Name: "c.test.com.", Type: "A", Zone: "test.com.", Content: "10.0.0.1", Ttl: 86400, ObjectType: "record"},
+		{Name: "d.other.com.", Type: "A", Zone: "other.com.", Content: "10.0.0.2", Ttl: 172800, ObjectType: "record"},
+		{Name: "a.example.com.", Type: "A", Zone: "example.com.", Content: "some comment", ObjectType: "comment"},
+	}
+
+	stats := ComputeStats(records, 0)
+
+	t.Run("total", func(t *testing.T) {
+		if stats.Total != 6 {
+			t.Errorf("expected 6 records, got %d", stats.Total)
+		}
+	})
+
+	t.Run("object types", func(t *testing.T) {
+		expected := []Count{{"comment", 1}, {"record", 5}}
+		if !reflect.DeepEqual(stats.ByObjectType, expected) {
+			t.Errorf("expected %v, got %v", expected, stats.ByObjectType)
+		}
+	})
+
+	t.Run("ttl distribution", func(t *testing.T) {
+		expected := []Count{{"<=60", 1}, {"61-300", 2}, {"301-3600", 0}, {"3601-86400", 1}, {">86400", 1}}
+		if !reflect.DeepEqual(stats.TTLs, expected) {
+			t.Errorf("expected %v, got %v", expected, stats.TTLs)
+		}
+	})
+
+	t.Run("top names", func(t *testing.T) {
+		if stats.TopNames[0] != (Count{"a.example.com.", 2}) {
+			t.Errorf("expected a.example.com. with 2 records first, got %v", stats.TopNames)
+		}
+	})
+
+	t.Run("content reuse", func(t *testing.T) {
+		expected := []Count{{"10.0.0.1", 3}}
+		if !reflect.DeepEqual(stats.ContentReuse, expected) {
+			t.Errorf("expected %v, got %v", expected, stats.ContentReuse)
+		}
+	})
+
+	t.Run("top zones", func(t *testing.T) {
+		limited := ComputeStats(records, 1)
+		expected := []Count{{"example.com.", 4}}
+		if !reflect.DeepEqual(limited.ByZone, expected) {
+			t.Errorf("expected %v, got %v", expected, limited.ByZone)
+		}
+		if len(limited.TopNames) != 1 {
+			t.Errorf("expected 1 top name, got %v", limited.TopNames)
+		}
+	})
+}
+
+func TestGenerateStatsCSV(t *testing.T) {
+	stats := Stats{
+		Total:  2,
+		ByType: []Count{{"A", 2}},
+		ByZone: []Count{{"example.com.", 2}},
+	}
+
+	expected := "Section;Key;Count\ntotal;;2\ntype;A;2\nzone;example.com.;2\n"
+	if output := generateStatsCSV(stats, ";"); output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestOutputStatsInvalidFormat(t *testing.T) {
+	if err := OutputStats(Stats{}, "xml", ";"); err == nil {
+		t.Error("expected error for invalid format")
+	}
+}
@@ -0,0 +1,73 @@
+package misc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/spf13/viper"
+)
+
+// writeTable prints rows as aligned columns below headers. Every column is
+// printed in its color from colors, without colors the columns are aligned
+// with a tabwriter.
+func writeTable(w io.Writer, headers []string, rows [][]string, colors []*color.Color) {
+	showHeader := !viper.GetBool("no-header")
+
+	if color.NoColor {
+		writer := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+		defer writer.Flush()
+		if showHeader {
+			fmt.Fprintln(writer, strings.Join(headers, TabDelimiter))
+		}
+		for _, row := range rows {
+			fmt.Fprintln(writer, strings.Join(row, TabDelimiter))
+		}
+		return
+	}
+
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	// every column but the last is padded, so trailing spaces are avoided
+	printRow := func(cells []string, colorOf func(i int) *color.Color) {
+		var line strings.Builder
+		for i, cell := range cells {
+			if i < len(cells)-1 {
+				line.WriteString(colorOf(i).Sprintf("%-*s", widths[i]+2, cell))
+			} else {
+				line.WriteString(colorOf(i).Sprint(cell))
+			}
+		}
+		fmt.Fprintln(w, line.String())
+	}
+
+	if showHeader {
+		printRow(headers, func(int) *color.Color { return headerColor })
+	}
+	for _, row := range rows {
+		printRow(row, func(i int) *color.Color { return colors[i] })
+	}
+}
+
+// joinRows joins the cells of every row with delimiter, with the headers
+// as first line unless disabled.
+func joinRows(headers []string, rows [][]string, delimiter string) string {
+	var output strings.Builder
+	if !viper.GetBool("no-header") {
+		output.WriteString(strings.Join(headers, delimiter) + "\n")
+	}
+	for _, row := range rows {
+		output.WriteString(strings.Join(row, delimiter) + "\n")
+	}
+	return output.String()
+}
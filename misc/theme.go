@@ -0,0 +1,198 @@
+package misc
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const DefaultTheme = "dark"
+
+// themes holds the attributes of the built-in color themes per element.
+var themes = map[string]map[string][]color.Attribute{
+	"dark": {
+		"header":    {color.FgHiWhite, color.Bold},
+		"zone":      {color.FgCyan},
+		"name":      {color.FgGreen},
+		"type":      {color.FgYellow},
+		"content":   {color.FgWhite},
+		"ttl":       {color.FgMagenta},
+		"object":    {color.FgBlue},
+		"add":       {color.FgGreen},
+		"remove":    {color.FgRed},
+		"modify":    {color.FgYellow},
+		"highlight": {color.Bold, color.ReverseVideo},
+		"disabled":  {color.FgHiBlack, color.CrossedOut},
+	},
+	"light": {
+		"header":    {color.FgBlack, color.Bold},
+		"zone":      {color.FgBlue},
+		"name":      {color.FgGreen},
+		"type":      {color.FgMagenta},
+		"content":   {color.FgBlack},
+		"ttl":       {color.FgRed},
+		"object":    {color.FgCyan},
+		"add":       {color.FgGreen},
+		"remove":    {color.FgRed},
+		"modify":    {color.FgBlue},
+		"highlight": {color.Bold, color.ReverseVideo},
+		"disabled":  {color.FgHiBlack, color.CrossedOut},
+	},
+	"mono": {
+		"header":    {color.Bold},
+		"zone":      {},
+		"name":      {},
+		"type":      {},
+		"content":   {},
+		"ttl":       {},
+		"object":    {},
+		"add":       {color.Bold},
+		"remove":    {},
+		"modify":    {color.Underline},
+		"highlight": {color.Bold, color.ReverseVideo},
+		"disabled":  {color.CrossedOut},
+	},
+}
+
+// themeTargets maps the element names used in themes and overrides to the
+// color variables used for output.
+var themeTargets = map[string]**color.Color{
+	"header":    &headerColor,
+	"zone":      &zoneColor,
+	"name":      &nameColor,
+	"type":      &typeColor,
+	"content":   &contentColor,
+	"ttl":       &ttlColor,
+	"object":    &objectColor,
+	"add":       &addColor,
+	"remove":    &removeColor,
+	"modify":    &modifyColor,
+	"highlight": &highlightColor,
+	"disabled":  &disabledColor,
+}
+
+var colorNames = map[string]color.Attribute{
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+}
+
+var attributeNames = map[string]color.Attribute{
+	"bold":       color.Bold,
+	"faint":      color.Faint,
+	"italic":     color.Italic,
+	"underline":  color.Underline,
+	"blink":      color.BlinkSlow,
+	"reverse":    color.ReverseVideo,
+	"crossedout": color.CrossedOut,
+}
+
+// ApplyTheme sets the output colors to the built-in theme name and applies
+// the per element overrides on top of it.
+func ApplyTheme(name string, overrides map[string]string) error {
+	if name == "" {
+		name = DefaultTheme
+	}
+	theme, ok := themes[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown theme: %s (valid options: %s)", name, strings.Join(themeNames(), ", "))
+	}
+
+	attrs := make(map[string][]color.Attribute, len(theme))
+	for element, a := range theme {
+		attrs[element] = a
+	}
+	for element, spec := range overrides {
+		element = strings.ToLower(element)
+		if _, ok := themeTargets[element]; !ok {
+			return fmt.Errorf("unknown color element: %s", element)
+		}
+		a, err := parseColorSpec(spec)
+		if err != nil {
+			return fmt.Errorf("color for %s: %w", element, err)
+		}
+		attrs[element] = a
+	}
+
+	for element, target := range themeTargets {
+		*target = color.New(attrs[element]...)
+	}
+	return nil
+}
+
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseColorSpec parses a color definition like "hi-red,bold" or
+// "black bg-white". Colors can be prefixed with "hi-" for the high intensity
+// variant and with "bg-" for the background. "none" resets all attributes.
+func parseColorSpec(spec string) ([]color.Attribute, error) {
+	attrs := []color.Attribute{}
+	fields := strings.FieldsFunc(strings.ToLower(spec), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	for _, f := range fields {
+		if f == "none" || f == "default" {
+			continue
+		}
+		if a, ok := attributeNames[f]; ok {
+			attrs = append(attrs, a)
+			continue
+		}
+
+		name := f
+		background := false
+		if rest, ok := strings.CutPrefix(name, "bg-"); ok {
+			name = rest
+			background = true
+		}
+		intense := false
+		if rest, ok := strings.CutPrefix(name, "hi-"); ok {
+			name = rest
+			intense = true
+		}
+		a, ok := colorNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown color or attribute: %s", f)
+		}
+		if intense {
+			a += color.FgHiBlack - color.FgBlack
+		}
+		if background {
+			a += color.BgBlack - color.FgBlack
+		}
+		attrs = append(attrs, a)
+	}
+	return attrs, nil
+}
+
+// ConfigureColor enables or disables colored output. The --no-color flag and
+// the NO_COLOR environment variable always disable colors, CLICOLOR_FORCE
+// enables them even if stdout is not a terminal.
+func ConfigureColor(noColor bool) {
+	color.NoColor = colorDisabled(noColor, color.NoColor, os.Getenv)
+}
+
+func colorDisabled(noColor, detected bool, getenv func(string) string) bool {
+	if noColor || getenv("NO_COLOR") != "" {
+		return true
+	}
+	if force := getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		return false
+	}
+	return detected
+}
@@ -0,0 +1,100 @@
+package misc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseColorSpec(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []color.Attribute
+		wantErr  bool
+	}{
+		{"red", []color.Attribute{color.FgRed}, false},
+		{"hi-red,bold", []color.Attribute{color.FgHiRed, color.Bold}, false},
+		{"black bg-white", []color.Attribute{color.FgBlack, color.BgWhite}, false},
+		{"bg-hi-blue", []color.Attribute{color.BgHiBlue}, false},
+		{"Underline", []color.Attribute{color.Underline}, false},
+		{"none", []color.Attribute{}, false},
+		{"purple", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			result, err := parseColorSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseColorSpec(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseColorSpec(%q) = %v, want %v", tt.spec, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplyTheme(t *testing.T) {
+	defer ApplyTheme(DefaultTheme, nil)
+
+	t.Run("built-in theme", func(t *testing.T) {
+		if err := ApplyTheme("light", nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !contentColor.Equals(color.New(color.FgBlack)) {
+			t.Error("expected black content color in light theme")
+		}
+	})
+
+	t.Run("override", func(t *testing.T) {
+		if err := ApplyTheme("dark", map[string]string{"content": "hi-black"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !contentColor.Equals(color.New(color.FgHiBlack)) {
+			t.Error("expected overridden content color")
+		}
+		if !zoneColor.Equals(color.New(color.FgCyan)) {
+			t.Error("expected zone color of the dark theme")
+		}
+	})
+
+	t.Run("unknown theme", func(t *testing.T) {
+		if err := ApplyTheme("solarized", nil); err == nil {
+			t.Error("expected error for unknown theme")
+		}
+	})
+
+	t.Run("unknown element", func(t *testing.T) {
+		if err := ApplyTheme("dark", map[string]string{"serial": "red"}); err == nil {
+			t.Error("expected error for unknown element")
+		}
+	})
+}
+
+func TestColorDisabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		noColor  bool
+		detected bool
+		env      map[string]string
+		expected bool
+	}{
+		{"terminal", false, false, nil, false},
+		{"no terminal", false, true, nil, true},
+		{"flag", true, false, nil, true},
+		{"NO_COLOR", false, false, map[string]string{"NO_COLOR": "1"}, true},
+		{"CLICOLOR_FORCE", false, true, map[string]string{"CLICOLOR_FORCE": "1"}, false},
+		{"CLICOLOR_FORCE=0", false, true, map[string]string{"CLICOLOR_FORCE": "0"}, true},
+		{"NO_COLOR wins", false, true, map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			if result := colorDisabled(tt.noColor, tt.detected, getenv); result != tt.expected {
+				t.Errorf("colorDisabled() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package misc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+// treeNode is a label in the DNS namespace with the records of the name it
+// represents.
+type treeNode struct {
+	label    string
+	records  []pdns.PDNSSearchResponseItem
+	children map[string]*treeNode
+}
+
+func newTreeNode(label string) *treeNode {
+	return &treeNode{label: label, children: make(map[string]*treeNode)}
+}
+
+func (n *treeNode) child(label string) *treeNode {
+	key := asciiLower(label)
+	c, ok := n.children[key]
+	if !ok {
+		c = newTreeNode(label)
+		n.children[key] = c
+	}
+	return c
+}
+
+// count returns the number of records in the subtree.
+func (n *treeNode) count() int {
+	total := len(n.records)
+	for _, c := range n.children {
+		total += c.count()
+	}
+	return total
+}
+
+// isDelegation reports whether the node has NS records. Only meaningful
+// for nodes below the zone apex.
+func (n *treeNode) isDelegation() bool {
+	return slices.ContainsFunc(n.records, func(r pdns.PDNSSearchResponseItem) bool {
+		return strings.EqualFold(r.Type, "NS")
+	})
+}
+
+func (n *treeNode) sortedChildren() []*treeNode {
+	children := make([]*treeNode, 0, len(n.children))
+	for _, c := range n.children {
+		children = append(children, c)
+	}
+	slices.SortFunc(children, func(a, b *treeNode) int {
+		return CompareNatural(asciiLower(a.label), asciiLower(b.label))
+	})
+	return children
+}
+
+// relativeLabels returns the labels of name below zone, closest to the zone
+// first. Names outside of the zone are returned as a single label.
+func relativeLabels(name, zone string) []string {
+	lowerName, lowerZone := asciiLower(name), asciiLower(zone)
+	if lowerName == lowerZone {
+		return nil
+	}
+	if !strings.HasSuffix(lowerName, "."+lowerZone) {
+		return []string{name}
+	}
+	labels := strings.Split(name[:len(name)-len(zone)-1], ".")
+	slices.Reverse(labels)
+	return labels
+}
+
+// buildTree arranges the records as one label tree per zone. The zones are
+// returned in DNS order.
+func buildTree(records []pdns.PDNSSearchResponseItem) []*treeNode {
+	zones := make(map[string]*treeNode)
+	for _, r := range records {
+		zone := r.Zone
+		if zone == "" {
+			zone = r.Name
+		}
+		root, ok := zones[asciiLower(zone)]
+		if !ok {
+			root = newTreeNode(zone)
+			zones[asciiLower(zone)] = root
+		}
+		// the zone node itself represents zone objects
+		if r.ObjectType == "zone" {
+			continue
+		}
+
+		node := root
+		for _, label := range relativeLabels(r.Name, zone) {
+			node = node.child(label)
+		}
+		node.records = append(node.records, r)
+	}
+
+	roots := make([]*treeNode, 0, len(zones))
+	for _, z := range zones {
+		roots = append(roots, z)
+	}
+	slices.SortFunc(roots, func(a, b *treeNode) int {
+		return CompareDNSNames(a.label, b.label)
+	})
+	return roots
+}
+
+func formatTreeRecord(r pdns.PDNSSearchResponseItem) string {
+	if r.ObjectType == "comment" {
+		return objectColor.Sprint("comment ") + contentColor.Sprint(r.Content)
+	}
+	if r.Disabled {
+		return disabledColor.Sprintf("%s %s (%d)%s", r.Type, r.Content, r.Ttl, disabledMarker)
+	}
+	return fmt.Sprintf("%s %s %s", typeColor.Sprint(r.Type), contentColor.Sprint(r.Content), ttlColor.Sprintf("(%d)", r.Ttl))
+}
+
+func renderTreeNode(w io.Writer, n *treeNode, prefix string) {
+	children := n.sortedChildren()
+	entries := len(n.records) + len(children)
+	i := 0
+	branch := func() (string, string) {
+		i++
+		if i == entries {
+			return prefix + "└── ", prefix + "    "
+		}
+		return prefix + "├── ", prefix + "│   "
+	}
+
+	for _, r := range n.records {
+		line, _ := branch()
+		fmt.Fprintf(w, "%s%s\n", line, formatTreeRecord(r))
+	}
+	for _, c := range children {
+		line, next := branch()
+		label := nameColor.Sprint(c.label)
+		if c.isDelegation() {
+			label += " " + typeColor.Sprint("[delegation]")
+		}
+		fmt.Fprintf(w, "%s%s (%d)\n", line, label, c.count())
+		renderTreeNode(w, c, next)
+	}
+}
+
+func renderTree(w io.Writer, roots []*treeNode) {
+	for _, root := range roots {
+		fmt.Fprintf(w, "%s (%d)\n", zoneColor.Sprint(root.label), root.count())
+		renderTreeNode(w, root, "")
+	}
+}
+
+// OutputToTree prints the records as a tree of DNS labels per zone with the
+// records attached to their names. Every node shows the number of records
+// below it, delegations are marked.
+func OutputToTree(records []pdns.PDNSSearchResponseItem) {
+	renderTree(os.Stdout, buildTree(records))
+}
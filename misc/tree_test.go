@@ -0,0 +1,65 @@
+package misc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/fatih/color"
+)
+
+func TestRelativeLabels(t *testing.T) {
+	tests := []struct {
+		name, zone string
+		expected   []string
+	}{
+		{"example.com.", "example.com.", nil},
+		{"fw-1.dmz.example.com.", "example.com.", []string{"dmz", "fw-1"}},
+		{"FW.Example.com.", "example.com.", []string{"FW"}},
+		{"fw.other.com.", "example.com.", []string{"fw.other.com."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := relativeLabels(tt.name, tt.zone)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("relativeLabels(%q, %q) = %v, want %v", tt.name, tt.zone, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderTree(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = oldNoColor }()
+
+	records := []pdns.PDNSSearchResponseItem{
+		{Zone: "example.com.", Name: "example.com.", ObjectType: "zone"},
+		{Zone: "example.com.", Name: "fw-1.dmz.example.com.", Type: "A", Content: "10.0.0.1", Ttl: 300, ObjectType: "record"},
+		{Zone: "example.com.", Name: "fw-1.dmz.example.com.", Type: "AAAA", Content: "2001:db8::1", Ttl: 300, ObjectType: "record"},
+		{Zone: "example.com.", Name: "sub.example.com.", Type: "NS", Content: "ns1.example.com.", Ttl: 3600, ObjectType: "record"},
+		{Zone: "example.com.", Name: "example.com.", Type: "MX", Content: "10 mail.example.com.", Ttl: 3600, ObjectType: "record"},
+		{Zone: "test.com.", Name: "fw.test.com.", Type: "A", Content: "10.1.0.1", Ttl: 60, ObjectType: "record"},
+	}
+
+	var buf strings.Builder
+	renderTree(&buf, buildTree(records))
+
+	expected := `example.com. (4)
+├── MX 10 mail.example.com. (3600)
+├── dmz (2)
+│   └── fw-1 (2)
+│       ├── A 10.0.0.1 (300)
+│       └── AAAA 2001:db8::1 (300)
+└── sub [delegation] (1)
+    └── NS ns1.example.com. (3600)
+test.com. (1)
+└── fw (1)
+    └── A 10.1.0.1 (60)
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected tree:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
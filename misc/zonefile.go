@@ -0,0 +1,63 @@
+package misc
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+// sortedRRSets returns the RRSets of the zone in canonical order: the SOA
+// first, then by name in DNS order and by type.
+func sortedRRSets(zone pdns.Zone) []pdns.RRSet {
+	rrsets := slices.Clone(zone.RRSets)
+	slices.SortStableFunc(rrsets, func(a, b pdns.RRSet) int {
+		if aSOA, bSOA := a.Type == "SOA", b.Type == "SOA"; aSOA != bSOA {
+			if aSOA {
+				return -1
+			}
+			return 1
+		}
+		if c := CompareDNSNames(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Type, b.Type)
+	})
+	return rrsets
+}
+
+// lineBreaks normalizes the line breaks of comments.
+var lineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// writeCommentLines writes text as comment lines, one per line of text, so
+// multi-line comments don't end up as zone data.
+func writeCommentLines(w io.Writer, text string) {
+	for _, line := range strings.Split(lineBreaks.Replace(text), "\n") {
+		fmt.Fprintf(w, "; %s\n", line)
+	}
+}
+
+// WriteZoneFile writes the zone in zone file format with absolute names.
+// Comments are written as comment lines above their RRSet, disabled records
+// are commented out.
+func WriteZoneFile(w io.Writer, zone pdns.Zone) {
+	fmt.Fprintf(w, "$ORIGIN %s\n", zone.Name)
+	for _, rrset := range sortedRRSets(zone) {
+		for _, c := range rrset.Comments {
+			text := c.Content
+			if c.Account != "" {
+				text += " (" + c.Account + ")"
+			}
+			writeCommentLines(w, text)
+		}
+		for _, r := range rrset.Records {
+			prefix := ""
+			if r.Disabled {
+				prefix = "; disabled: "
+			}
+			fmt.Fprintf(w, "%s%s\t%d\tIN\t%s\t%s\n", prefix, rrset.Name, rrset.TTL, rrset.Type, r.Content)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package misc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+func TestWriteZoneFile(t *testing.T) {
+	zone := pdns.Zone{
+		Name: "example.com.",
+		RRSets: []pdns.RRSet{
+			{Name: "www.example.com.", Type: "CNAME", TTL: 300, Records: []pdns.Record{{Content: "fw-1.example.com."}}},
+			{Name: "fw-1.example.com.", Type: "A", TTL: 300,
+				Records:  []pdns.Record{{Content: "10.0.0.1"}, {Content: "10.0.0.2", Disabled: true}},
+				Comments: []pdns.Comment{{Content: "primary firewall", Account: "alice"}},
+			},
+			{Name: "example.com.", Type: "NS", TTL: 3600, Records: []pdns.Record{{Content: "ns1.example.com."}}},
+			{Name: "example.com.", Type: "SOA", TTL: 3600, Records: []pdns.Record{{Content: "ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600"}}},
+		},
+	}
+
+	var buf strings.Builder
+	WriteZoneFile(&buf, zone)
+
+	expected := `$ORIGIN example.com.
+example.com.	3600	IN	SOA	ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600
+example.com.	3600	IN	NS	ns1.example.com.
+; primary firewall (alice)
+fw-1.example.com.	300	IN	A	10.0.0.1
+; disabled: fw-1.example.com.	300	IN	A	10.0.0.2
+www.example.com.	300	IN	CNAME	fw-1.example.com.
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected zone file:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteZoneFileMultiLineComment(t *testing.T) {
+	zone := pdns.Zone{
+		Name: "example.com.",
+		RRSets: []pdns.RRSet{
+			{Name: "fw-1.example.com.", Type: "A", TTL: 300,
+				Records:  []pdns.Record{{Content: "10.0.0.1"}},
+				Comments: []pdns.Comment{{Content: "primary firewall\nevil.example.com. 300 IN A 10.6.6.6\r\nsee INC-1234", Account: "alice"}},
+			},
+		},
+	}
+
+	var buf strings.Builder
+	WriteZoneFile(&buf, zone)
+
+	expected := `$ORIGIN example.com.
+; primary firewall
+; evil.example.com. 300 IN A 10.6.6.6
+; see INC-1234 (alice)
+fw-1.example.com.	300	IN	A	10.0.0.1
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected zone file:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
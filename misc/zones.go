@@ -0,0 +1,83 @@
+package misc
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/fatih/color"
+)
+
+var zoneHeaders = []string{"Name", "Kind", "Serial", "Notified Serial", "Masters", "DNSSEC", "Account"}
+
+var zoneSortFields = map[string]func(a, b pdns.Zone) int{
+	"name": func(a, b pdns.Zone) int {
+		return CompareDNSNames(a.Name, b.Name)
+	},
+	"kind": func(a, b pdns.Zone) int {
+		return strings.Compare(a.Kind, b.Kind)
+	},
+	"serial": func(a, b pdns.Zone) int {
+		return cmp.Compare(a.Serial, b.Serial)
+	},
+	"account": func(a, b pdns.Zone) int {
+		return CompareNatural(a.Account, b.Account)
+	},
+	"dnssec": func(a, b pdns.Zone) int {
+		return cmp.Compare(strconv.FormatBool(a.DNSSec), strconv.FormatBool(b.DNSSec))
+	},
+}
+
+// SortZones sorts the zones like SortRecords. Zones equal in all fields are
+// ordered by name.
+func SortZones(zones []pdns.Zone, sortBy string) error {
+	keys, err := parseSortKeys(sortBy, zoneSortFields, "name, kind, serial, account, dnssec")
+	if err != nil {
+		return err
+	}
+	sortByKeys(zones, keys, zoneSortFields, "name")
+	return nil
+}
+
+func zoneRow(z pdns.Zone) []string {
+	return []string{
+		z.Name,
+		z.Kind,
+		strconv.Itoa(z.Serial),
+		strconv.Itoa(z.NotifiedSerial),
+		strings.Join(z.Masters, ","),
+		strconv.FormatBool(z.DNSSec),
+		z.Account,
+	}
+}
+
+// OutputZones prints the zones as table, csv, raw or json.
+func OutputZones(zones []pdns.Zone, format, delimiter string) error {
+	rows := make([][]string, len(zones))
+	for i, z := range zones {
+		rows[i] = zoneRow(z)
+	}
+
+	switch format {
+	case "table":
+		colors := []*color.Color{nameColor, typeColor, ttlColor, ttlColor, contentColor, objectColor, zoneColor}
+		writeTable(os.Stdout, zoneHeaders, rows, colors)
+	case "csv":
+		fmt.Print(joinRows(zoneHeaders, rows, delimiter))
+	case "raw":
+		fmt.Print(joinRows(zoneHeaders, rows, DefaultDelimiter))
+	case "json":
+		output, err := json.MarshalIndent(zones, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling to JSON: %w", err)
+		}
+		fmt.Println(string(output))
+	default:
+		return fmt.Errorf("invalid output format for zones: %s (valid options: table, csv, raw, json)", format)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package misc
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+	"github.com/fatih/color"
+)
+
+func TestSortZones(t *testing.T) {
+	zones := []pdns.Zone{
+		{Name: "example.org.", Kind: "Native", Serial: 3},
+		{Name: "b.example.com.", Kind: "Slave", Serial: 1},
+		{Name: "example.com.", Kind: "Native", Serial: 2},
+	}
+
+	tests := []struct {
+		sortBy   string
+		expected []string
+	}{
+		{"name", []string{"example.com.", "b.example.com.", "example.org."}},
+		{"-serial", []string{"example.org.", "example.com.", "b.example.com."}},
+		{"kind", []string{"example.com.", "example.org.", "b.example.com."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			if err := SortZones(zones, tt.sortBy); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var names []string
+			for _, z := range zones {
+				names = append(names, z.Name)
+			}
+			if strings.Join(names, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	if err := SortZones(zones, "ttl"); err == nil {
+		t.Error("expected error for invalid sort field")
+	}
+}
+
+var ansiCodes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestWriteTable(t *testing.T) {
+	oldNoColor := color.NoColor
+	defer func() { color.NoColor = oldNoColor }()
+
+	zones := []pdns.Zone{
+		{Name: "example.com.", Kind: "Slave", Serial: 2024050201, NotifiedSerial: 2024050201, Masters: []string{"10.0.0.1", "10.0.0.2"}, DNSSec: true},
+		{Name: "example.org.", Kind: "Native", Serial: 1},
+	}
+	rows := [][]string{zoneRow(zones[0]), zoneRow(zones[1])}
+	colors := make([]*color.Color, len(zoneHeaders))
+	for i := range colors {
+		colors[i] = color.New()
+	}
+
+	for _, noColor := range []bool{true, false} {
+		color.NoColor = noColor
+		var buf strings.Builder
+		writeTable(&buf, zoneHeaders, rows, colors)
+
+		output := ansiCodes.ReplaceAllString(buf.String(), "")
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("expected 3 lines, got %q", output)
+		}
+		// the serial column starts at the same position in every line
+		serialAt := strings.Index(lines[0], "Serial")
+		if strings.Index(lines[1], "2024050201") != serialAt || strings.Index(lines[2], "1 ") != serialAt {
+			t.Errorf("expected aligned columns (no color %v), got:\n%s", noColor, output)
+		}
+		if !strings.Contains(lines[1], "10.0.0.1,10.0.0.2") {
+			t.Errorf("expected joined masters, got %q", lines[1])
+		}
+	}
+}
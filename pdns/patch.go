@@ -0,0 +1,147 @@
+package pdns
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// RRSetChange is a change of a single RRSet. Before is nil for a new RRSet,
+// After is nil for a deleted RRSet.
+type RRSetChange struct {
+	Zone   string `json:"zone"`
+	Before *RRSet `json:"before"`
+	After  *RRSet `json:"after"`
+}
+
+// Equal reports whether both RRSets have the same TTL, records and comments.
+// The order of records and comments doesn't matter.
+func (r RRSet) Equal(o RRSet) bool {
+	if !strings.EqualFold(r.Name, o.Name) || !strings.EqualFold(r.Type, o.Type) || r.TTL != o.TTL {
+		return false
+	}
+	return sameElements(r.Records, o.Records) && sameElements(r.Comments, o.Comments)
+}
+
+func sameElements[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[T]int, len(a))
+	for _, v := range a {
+		counts[v]++
+	}
+	for _, v := range b {
+		if counts[v] == 0 {
+			return false
+		}
+		counts[v]--
+	}
+	return true
+}
+
+// RRSetPatch is an RRSet in a PATCH request of a zone. TTL is only nil for
+// DELETE, a REPLACE always sends it, as a TTL of 0 is valid.
+type RRSetPatch struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	TTL        *int     `json:"ttl,omitempty"`
+	Changetype string   `json:"changetype"`
+	Records    []Record `json:"records,omitempty"`
+	// Comments are only replaced if set, an empty list deletes all
+	// comments of the RRSet.
+	Comments *[]Comment `json:"comments,omitempty"`
+}
+
+// ZonePatch is the body of a PATCH request of a zone.
+type ZonePatch struct {
+	RRSets []RRSetPatch `json:"rrsets"`
+}
+
+// NewZonePatch returns the patch applying the changes. Changes with an
+// After RRSet replace the RRSet, changes without delete it. Comments of a
+// replaced RRSet are kept unless After has non-nil Comments.
+func NewZonePatch(changes []RRSetChange) ZonePatch {
+	patch := ZonePatch{RRSets: []RRSetPatch{}}
+	for _, c := range changes {
+		if c.After == nil {
+			patch.RRSets = append(patch.RRSets, RRSetPatch{Name: c.Before.Name, Type: c.Before.Type, Changetype: "DELETE"})
+			continue
+		}
+		rrset := RRSetPatch{
+			Name:       c.After.Name,
+			Type:       c.After.Type,
+			TTL:        &c.After.TTL,
+			Changetype: "REPLACE",
+			Records:    c.After.Records,
+		}
+		if c.After.Comments != nil {
+			rrset.Comments = &c.After.Comments
+		}
+		patch.RRSets = append(patch.RRSets, rrset)
+	}
+	return patch
+}
+
+// PatchZone applies the patch to the zone.
+func (p *PDNSAPI) PatchZone(zone string, patch ZonePatch) error {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("marshaling patch: %w", err)
+	}
+
+	zone = CanonicalName(zone)
+	req, err := p.newRequest("PATCH", zonePath(zone), nil, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("patching zone %s: %w", zone, apiError(resp))
+	}
+	return nil
+}
+
+// apiError returns the error message of a failed request, which PowerDNS
+// sends as JSON object with an error field.
+func apiError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		return fmt.Errorf("%s (status code %d)", body.Error, resp.StatusCode)
+	}
+	return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+// ZoneURL returns the API URL of the zone.
+func (p *PDNSAPI) ZoneURL(zone string) string {
+	return p.URL + zonePath(CanonicalName(zone))
+}
+
+// FindZone returns the zone with the longest name that name is part of.
+func FindZone(zones []Zone, name string) (Zone, bool) {
+	name = strings.ToLower(CanonicalName(name))
+	var found Zone
+	longest := 0
+	for _, z := range zones {
+		zoneName := strings.ToLower(CanonicalName(z.Name))
+		if name != zoneName && !strings.HasSuffix(name, "."+zoneName) {
+			continue
+		}
+		if len(zoneName) > longest {
+			found, longest = z, len(zoneName)
+		}
+	}
+	return found, longest > 0
+}
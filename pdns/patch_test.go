@@ -0,0 +1,123 @@
+package pdns
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewZonePatch(t *testing.T) {
+	before := &RRSet{Name: "fw.example.com.", Type: "A", TTL: 300, Records: []Record{{Content: "10.0.0.1"}}}
+	changes := []RRSetChange{
+		{Zone: "example.com.", Before: before, After: &RRSet{Name: "fw.example.com.", Type: "A", TTL: 60, Records: []Record{{Content: "10.0.0.2"}}}},
+		{Zone: "example.com.", Before: &RRSet{Name: "old.example.com.", Type: "CNAME"}},
+		{Zone: "example.com.", Before: before, After: &RRSet{Name: "fw.example.com.", Type: "A", TTL: 300, Records: before.Records, Comments: []Comment{}}},
+		{Zone: "example.com.", After: &RRSet{Name: "now.example.com.", Type: "A", TTL: 0, Records: []Record{{Content: "10.0.0.3"}}}},
+	}
+
+	data, err := json.Marshal(NewZonePatch(changes))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"rrsets":[` +
+		`{"name":"fw.example.com.","type":"A","ttl":60,"changetype":"REPLACE","records":[{"content":"10.0.0.2","disabled":false}]},` +
+		`{"name":"old.example.com.","type":"CNAME","changetype":"DELETE"},` +
+		`{"name":"fw.example.com.","type":"A","ttl":300,"changetype":"REPLACE","records":[{"content":"10.0.0.1","disabled":false}],"comments":[]},` +
+		`{"name":"now.example.com.","type":"A","ttl":0,"changetype":"REPLACE","records":[{"content":"10.0.0.3","disabled":false}]}]}`
+	if string(data) != expected {
+		t.Errorf("unexpected patch:\n%s\nwant:\n%s", data, expected)
+	}
+}
+
+func TestPatchZone(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		if strings.Contains(body, "invalid") {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error": "Record invalid.example.com./A '300.0.0.1': Parsing record content failed"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewPDNSAPI(server.URL, "secret")
+	patch := ZonePatch{RRSets: []RRSetPatch{{Name: "fw.example.com.", Type: "A", Changetype: "DELETE"}}}
+	if err := client.PatchZone("example.com", patch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if method != "PATCH" || path != "/api/v1/servers/localhost/zones/example.com." {
+		t.Errorf("unexpected request %s %s", method, path)
+	}
+	if !strings.Contains(body, `"changetype":"DELETE"`) {
+		t.Errorf("unexpected body %s", body)
+	}
+
+	patch.RRSets[0].Name = "invalid.example.com."
+	err := client.PatchZone("example.com.", patch)
+	if err == nil || !strings.Contains(err.Error(), "Parsing record content failed") {
+		t.Errorf("expected error message of the API, got %v", err)
+	}
+}
+
+func TestFindZone(t *testing.T) {
+	zones := []Zone{{Name: "example.com."}, {Name: "dmz.example.com."}, {Name: "ample.com."}}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"fw.dmz.example.com.", "dmz.example.com."},
+		{"dmz.example.com", "dmz.example.com."},
+		{"www.example.com.", "example.com."},
+		{"WWW.Example.COM.", "example.com."},
+		{"www.ample.com.", "ample.com."},
+		{"www.example.org.", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, ok := FindZone(zones, tt.name)
+			if zone.Name != tt.expected || ok != (tt.expected != "") {
+				t.Errorf("expected %q, got %q (%v)", tt.expected, zone.Name, ok)
+			}
+		})
+	}
+}
+
+func TestRRSetEqual(t *testing.T) {
+	a := RRSet{Name: "fw.example.com.", Type: "A", TTL: 300,
+		Records:  []Record{{Content: "10.0.0.1"}, {Content: "10.0.0.2"}},
+		Comments: []Comment{{Content: "primary firewall"}},
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(r *RRSet)
+		expected bool
+	}{
+		{"same", func(r *RRSet) {}, true},
+		{"record order", func(r *RRSet) { r.Records = []Record{{Content: "10.0.0.2"}, {Content: "10.0.0.1"}} }, true},
+		{"ttl", func(r *RRSet) { r.TTL = 60 }, false},
+		{"content", func(r *RRSet) { r.Records = []Record{{Content: "10.0.0.1"}, {Content: "10.0.0.3"}} }, false},
+		{"disabled", func(r *RRSet) { r.Records = []Record{{Content: "10.0.0.1", Disabled: true}, {Content: "10.0.0.2"}} }, false},
+		{"comments", func(r *RRSet) { r.Comments = nil }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := a
+			tt.modify(&b)
+			if got := a.Equal(b); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
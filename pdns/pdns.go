@@ -1,11 +1,19 @@
 package pdns
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -14,6 +22,9 @@ import (
 
 const maxSearchResults = 9999999
 
+// maxZoneRequests bounds the zones AddDetails fetches at the same time.
+const maxZoneRequests = 8
+
 type PDNSSearchResponseItem struct {
 	Name       string `json:"name"`
 	Type       string `json:"type"`
@@ -21,6 +32,9 @@ type PDNSSearchResponseItem struct {
 	ObjectType string `json:"object_type"`
 	Zone       string `json:"zone"`
 	Ttl        int    `json:"ttl"`
+	Disabled   bool   `json:"disabled,omitempty"`
+	// Comments of the record's RRSet, only set by AddDetails.
+	Comments []Comment `json:"comments,omitempty"`
 }
 
 type PDNSAPI struct {
@@ -29,44 +43,236 @@ type PDNSAPI struct {
 	Client    http.Client
 	Timeout   time.Duration
 	UserAgent string
+
+	// Limit stops searches after this many results, 0 means no limit.
+	Limit int
+
+	// ConditionalRequests keeps the results of searches with an ETag and
+	// asks the API with If-None-Match whether they changed.
+	ConditionalRequests bool
+
+	cacheMu  sync.Mutex
+	cache    map[string]cachedSearch
+	etagSeen atomic.Bool
+}
+
+// cachedSearch is a search result kept for conditional requests.
+type cachedSearch struct {
+	etag  string
+	items []PDNSSearchResponseItem
+}
+
+// Zone is a zone as listed by the API.
+type Zone struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Kind           string   `json:"kind"`
+	Serial         int      `json:"serial"`
+	NotifiedSerial int      `json:"notified_serial"`
+	Masters        []string `json:"masters"`
+	DNSSec         bool     `json:"dnssec"`
+	Account        string   `json:"account"`
+	// RRSets are only returned for a single zone, see GetZone.
+	RRSets []RRSet `json:"rrsets,omitempty"`
+}
+
+// RRSet holds all records of a zone with the same name and type.
+type RRSet struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	TTL      int       `json:"ttl"`
+	Records  []Record  `json:"records"`
+	Comments []Comment `json:"comments"`
+}
+
+type Record struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Comment is a comment of an RRSet. Without ModifiedAt, PowerDNS sets the
+// time a patched comment is stored.
+type Comment struct {
+	Content    string `json:"content"`
+	Account    string `json:"account"`
+	ModifiedAt int64  `json:"modified_at,omitempty"`
+}
+
+// Records returns the records of the zone's RRSets as search results.
+func (z Zone) Records() []PDNSSearchResponseItem {
+	var records []PDNSSearchResponseItem
+	for _, rrset := range z.RRSets {
+		records = append(records, rrset.Items(z.Name)...)
+	}
+	return records
+}
+
+// FindRRSet returns the RRSet with the name and type.
+func (z Zone) FindRRSet(name, rType string) (RRSet, bool) {
+	for _, rrset := range z.RRSets {
+		if strings.EqualFold(rrset.Name, name) && strings.EqualFold(rrset.Type, rType) {
+			return rrset, true
+		}
+	}
+	return RRSet{}, false
+}
+
+// Items returns the records of the RRSet in zone as search results.
+func (r RRSet) Items(zone string) []PDNSSearchResponseItem {
+	items := make([]PDNSSearchResponseItem, 0, len(r.Records))
+	for _, record := range r.Records {
+		items = append(items, PDNSSearchResponseItem{
+			Name:       r.Name,
+			Type:       r.Type,
+			Content:    record.Content,
+			ObjectType: "record",
+			Zone:       zone,
+			Ttl:        r.TTL,
+			Disabled:   record.Disabled,
+			Comments:   r.Comments,
+		})
+	}
+	return items
 }
 
 func (p *PDNSAPI) Search(query string, objectType string) ([]PDNSSearchResponseItem, error) {
+	items := []PDNSSearchResponseItem{}
+	err := p.SearchStream(context.Background(), query, objectType, func(item PDNSSearchResponseItem) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// SearchStream searches like Search, but decodes the response item by item
+// and passes every item to yield as soon as it arrives. An error returned
+// by yield stops the search and is returned.
+func (p *PDNSAPI) SearchStream(ctx context.Context, query string, objectType string, yield func(PDNSSearchResponseItem) error) error {
 	if objectType == "" {
 		objectType = "all"
 	}
+	maxResults := maxSearchResults
+	if p.Limit > 0 {
+		maxResults = p.Limit
+	}
 	req, err := p.newRequest("GET", "/api/v1/servers/localhost/search-data", map[string]any{
 		"q":           query,
 		"object_type": objectType,
-		"max":         maxSearchResults,
-	})
+		"max":         maxResults,
+	}, nil)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	key := req.URL.String()
+	cached, isCached := p.cachedSearch(key)
+	if isCached {
+		req.Header.Set("If-None-Match", cached.etag)
 	}
 
 	resp, err := p.Client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && isCached {
+		log.Debugf("%s not modified", query)
+		for _, item := range cached.items {
+			if err := yield(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 	if resp.StatusCode == http.StatusNotFound {
-		return []PDNSSearchResponseItem{}, nil
+		return nil
 	} else if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
+	etag := resp.Header.Get("ETag")
+	if etag != "" {
+		p.etagSeen.Store(true)
+	}
+	// keep the items for the next conditional request
+	keep := p.ConditionalRequests && etag != ""
 	var items []PDNSSearchResponseItem
-	err = decodeResponse(resp, &items)
+
+	body, err := responseBody(resp)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	err = decodeArray(body, func(item PDNSSearchResponseItem) error {
+		if keep {
+			items = append(items, item)
+		}
+		return yield(item)
+	})
+	if err != nil {
+		return err
+	}
+
+	if keep {
+		p.cacheMu.Lock()
+		if p.cache == nil {
+			p.cache = make(map[string]cachedSearch)
+		}
+		p.cache[key] = cachedSearch{etag: etag, items: items}
+		p.cacheMu.Unlock()
+	}
+	return nil
+}
+
+func (p *PDNSAPI) cachedSearch(key string) (cachedSearch, bool) {
+	if !p.ConditionalRequests {
+		return cachedSearch{}, false
+	}
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	cached, ok := p.cache[key]
+	return cached, ok
+}
+
+// SupportsETags reports whether any search response carried an ETag.
+func (p *PDNSAPI) SupportsETags() bool {
+	return p.etagSeen.Load()
+}
+
+// ListZones returns all zones of the server. The request is canceled when
+// ctx ends.
+func (p *PDNSAPI) ListZones(ctx context.Context) ([]Zone, error) {
+	req, err := p.newRequest("GET", "/api/v1/servers/localhost/zones", nil, nil)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 
-	return items, nil
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var zones []Zone
+	if err := decodeResponse(resp, &zones); err != nil {
+		return nil, err
+	}
+	return zones, nil
 }
 
-func (p *PDNSAPI) newRequest(method string, path string, params map[string]any) (*http.Request, error) {
+func (p *PDNSAPI) newRequest(method string, path string, params map[string]any, body io.Reader) (*http.Request, error) {
 	url := p.URL + path
-	req, err := http.NewRequest(method, url, nil)
+	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +281,7 @@ func (p *PDNSAPI) newRequest(method string, path string, params map[string]any)
 	log.Debugf("params: %v", params)
 
 	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Accept-Encoding", "gzip")
 	req.Header.Add("Content-Type", "application/json")
 
 	if p.UserAgent != "" {
@@ -104,10 +311,62 @@ func NewPDNSAPI(url, apiKey string) *PDNSAPI {
 
 func decodeResponse(resp *http.Response, v any) error {
 	defer resp.Body.Close()
-	return json.NewDecoder(resp.Body).Decode(v)
+	body, err := responseBody(resp)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(v)
+}
+
+// responseBody returns the body of resp, decompressed if the API sent it
+// gzip compressed. As Accept-Encoding is set explicitly, the transport
+// leaves the decompression to us.
+func responseBody(resp *http.Response) (io.ReadCloser, error) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return io.NopCloser(resp.Body), nil
+	}
+	body, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing response: %w", err)
+	}
+	return body, nil
+}
+
+// decodeArray decodes a JSON array of items token by token, so only a
+// single item is held in memory at a time, and passes every item to yield.
+func decodeArray[T any](r io.Reader, yield func(T) error) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("decoding response: expected array, got %v", tok)
+	}
+
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return fmt.Errorf("decoding response: %w", err)
+		}
+		if err := yield(item); err != nil {
+			return err
+		}
+	}
+
+	// closing bracket
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
 }
 
 func GetPDNSRecords(ctx context.Context, client *PDNSAPI, search []string, objectType string) ([]PDNSSearchResponseItem, error) {
+	// canceled once the limit is reached to stop the remaining searches
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	g, ctx := errgroup.WithContext(ctx)
 	recordsChan := make(chan PDNSSearchResponseItem)
 
@@ -120,21 +379,18 @@ func GetPDNSRecords(ctx context.Context, client *PDNSAPI, search []string, objec
 		}
 		g.Go(func() error {
 			log.Infof("searching for term %s", term)
-			records, err := client.Search(term, objectType)
-			log.Debug("finished request")
-			if err != nil {
-				return err
-			}
-			for _, record := range records {
+			// records are added to the channel while the response is decoded
+			err := client.SearchStream(ctx, term, objectType, func(record PDNSSearchResponseItem) error {
 				select {
 				case <-ctx.Done():
 					return ctx.Err()
 				case recordsChan <- record:
 					log.Debugf("Adding %v", record)
+					return nil
 				}
-			}
-			log.Debug("added records to channel")
-			return nil
+			})
+			log.Debug("finished request")
+			return err
 		})
 	}
 
@@ -147,16 +403,77 @@ func GetPDNSRecords(ctx context.Context, client *PDNSAPI, search []string, objec
 	var combinedRecords []PDNSSearchResponseItem
 	for record := range recordsChan {
 		combinedRecords = append(combinedRecords, record)
+		if client.Limit > 0 && len(combinedRecords) >= client.Limit {
+			log.Infof("limit of %d results reached", client.Limit)
+			cancel()
+			break
+		}
 	}
 
 	// Wait for all operations to complete and collect any errors
 	if err := g.Wait(); err != nil {
+		if client.Limit > 0 && len(combinedRecords) >= client.Limit && errors.Is(err, context.Canceled) {
+			return combinedRecords, nil
+		}
 		return nil, err
 	}
 
 	return combinedRecords, nil
 }
 
+// AddDetails adds the comments of their RRSets and the disabled flag to the
+// records. The zones of the records are fetched concurrently, at most
+// maxZoneRequests at a time.
+func AddDetails(ctx context.Context, client *PDNSAPI, records []PDNSSearchResponseItem) error {
+	var zones []string
+	for _, r := range records {
+		if r.Zone != "" && !slices.Contains(zones, r.Zone) {
+			zones = append(zones, r.Zone)
+		}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxZoneRequests)
+	fetched := make([]*Zone, len(zones))
+	for i, name := range zones {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			zone, err := client.GetZone(name)
+			if err != nil {
+				return err
+			}
+			fetched[i] = zone
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	rrsets := make(map[string]RRSet)
+	for _, zone := range fetched {
+		for _, rrset := range zone.RRSets {
+			rrsets[zone.Name+"|"+rrset.Name+"|"+rrset.Type] = rrset
+		}
+	}
+	for i := range records {
+		r := &records[i]
+		rrset, ok := rrsets[r.Zone+"|"+r.Name+"|"+r.Type]
+		if !ok || r.ObjectType == "zone" {
+			continue
+		}
+		r.Comments = rrset.Comments
+		for _, record := range rrset.Records {
+			if record.Content == r.Content {
+				r.Disabled = record.Disabled
+			}
+		}
+	}
+	return nil
+}
+
 // CheckStringOnlyHostname returns true if the input is only a hostname label
 // (e.g. "ns1") and not a FQDN, IP, or wildcard pattern.
 func CheckStringOnlyHostname(input string) bool {
@@ -175,3 +492,132 @@ func FilterRecordsOnType(records []PDNSSearchResponseItem, rType string) []PDNSS
 	log.Debugf("Filtered to %d records", len(filtered))
 	return filtered
 }
+
+// GetZone returns the zone with all its RRSets.
+func (p *PDNSAPI) GetZone(zone string) (*Zone, error) {
+	zone = CanonicalName(zone)
+	req, err := p.newRequest("GET", zonePath(zone), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity {
+		resp.Body.Close()
+		return nil, fmt.Errorf("zone %s not found", zone)
+	} else if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var z Zone
+	if err := decodeResponse(resp, &z); err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+// CanonicalName returns the name with a trailing dot.
+func CanonicalName(name string) string {
+	if !strings.HasSuffix(name, ".") {
+		return name + "."
+	}
+	return name
+}
+
+func zonePath(zone string) string {
+	return "/api/v1/servers/localhost/zones/" + url.PathEscape(zone)
+}
+
+// MatchZones returns the zones whose name matches pattern. Like search
+// terms, a pattern that is only a hostname label matches anywhere in the
+// name and any other pattern matches the start of the name, except for a
+// pattern ending with a dot, which has to match the whole name. Matching is
+// case insensitive.
+func MatchZones(zones []Zone, pattern string) ([]Zone, error) {
+	pattern = strings.ToLower(pattern)
+	if CheckStringOnlyHostname(pattern) {
+		pattern = "*" + pattern + "*"
+	} else if !strings.HasSuffix(pattern, ".") {
+		pattern = pattern + "*"
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+
+	matched := []Zone{}
+	for _, z := range zones {
+		if ok, _ := path.Match(pattern, strings.ToLower(z.Name)); ok {
+			matched = append(matched, z)
+		}
+	}
+	return matched, nil
+}
+
+func FilterZonesOnKind(zones []Zone, kind string) []Zone {
+	filtered := []Zone{}
+	for _, z := range zones {
+		if strings.EqualFold(z.Kind, kind) {
+			filtered = append(filtered, z)
+		}
+	}
+	return filtered
+}
+
+func FilterZonesOnDNSSec(zones []Zone, dnssec bool) []Zone {
+	filtered := []Zone{}
+	for _, z := range zones {
+		if z.DNSSec == dnssec {
+			filtered = append(filtered, z)
+		}
+	}
+	return filtered
+}
+
+// FilterRecordsOnDisabled returns the records that are disabled, or those
+// that are not if disabled is false.
+func FilterRecordsOnDisabled(records []PDNSSearchResponseItem, disabled bool) []PDNSSearchResponseItem {
+	filtered := []PDNSSearchResponseItem{}
+	for _, r := range records {
+		if r.Disabled == disabled {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// JoinComments moves the comment objects of the search results into the
+// comments of the records of their RRset, so the comments are shown next to
+// the records. A comment without type belongs to all records of its name.
+// Comment objects without records in the results are kept.
+func JoinComments(records []PDNSSearchResponseItem) []PDNSSearchResponseItem {
+	joined := make([]PDNSSearchResponseItem, 0, len(records))
+	var comments []PDNSSearchResponseItem
+	for _, r := range records {
+		if r.ObjectType == "comment" {
+			comments = append(comments, r)
+		} else {
+			joined = append(joined, r)
+		}
+	}
+
+	for _, c := range comments {
+		found := false
+		for i, r := range joined {
+			if r.ObjectType != "record" || r.Zone != c.Zone || r.Name != c.Name || (c.Type != "" && r.Type != c.Type) {
+				continue
+			}
+			found = true
+			if !slices.ContainsFunc(r.Comments, func(existing Comment) bool { return existing.Content == c.Content }) {
+				joined[i].Comments = append(slices.Clone(r.Comments), Comment{Content: c.Content})
+			}
+		}
+		if !found {
+			joined = append(joined, c)
+		}
+	}
+	return joined
+}
@@ -1,7 +1,17 @@
 package pdns
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestCheckStringOnlyHostname(t *testing.T) {
@@ -74,3 +84,322 @@ func TestFilterRecordsOnType(t *testing.T) {
 		}
 	})
 }
+
+func TestSearchConditionalRequests(t *testing.T) {
+	calls, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		json.NewEncoder(w).Encode([]PDNSSearchResponseItem{{Name: "a.example.com.", Type: "A", Content: "10.0.0.1"}})
+	}))
+	defer server.Close()
+
+	client := NewPDNSAPI(server.URL, "secret")
+	client.ConditionalRequests = true
+
+	for range 2 {
+		items, err := client.Search("a*", "all")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(items) != 1 || items[0].Content != "10.0.0.1" {
+			t.Errorf("expected cached result, got %v", items)
+		}
+	}
+	if calls != 2 || notModified != 1 {
+		t.Errorf("expected 2 calls with 1 not modified, got %d and %d", calls, notModified)
+	}
+	if !client.SupportsETags() {
+		t.Error("expected ETag support to be detected")
+	}
+}
+
+func TestSearchWithoutConditionalRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Error("expected no If-None-Match header")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		json.NewEncoder(w).Encode([]PDNSSearchResponseItem{})
+	}))
+	defer server.Close()
+
+	client := NewPDNSAPI(server.URL, "secret")
+	for range 2 {
+		if _, err := client.Search("a*", "all"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestListZones(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/servers/localhost/zones" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`[{"id": "example.com.", "name": "example.com.", "serial": 2024050201}]`))
+	}))
+	defer server.Close()
+
+	zones, err := NewPDNSAPI(server.URL, "secret").ListZones(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(zones) != 1 || zones[0].Name != "example.com." || zones[0].Serial != 2024050201 {
+		t.Errorf("unexpected zones %v", zones)
+	}
+}
+
+func TestListZonesCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := NewPDNSAPI(server.URL, "secret").ListZones(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request to be canceled, got %v", err)
+	}
+}
+
+func TestSearchGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("expected gzip to be requested, got %q", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		json.NewEncoder(gz).Encode([]PDNSSearchResponseItem{{Name: "a.example.com.", Type: "A", Content: "10.0.0.1"}})
+	}))
+	defer server.Close()
+
+	items, err := NewPDNSAPI(server.URL, "secret").Search("a*", "all")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "a.example.com." {
+		t.Errorf("unexpected items %v", items)
+	}
+}
+
+func TestDecodeArray(t *testing.T) {
+	var names []string
+	err := decodeArray(strings.NewReader(`[{"name": "a."}, {"name": "b."}]`), func(item PDNSSearchResponseItem) error {
+		names = append(names, item.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(names, ",") != "a.,b." {
+		t.Errorf("expected a.,b., got %v", names)
+	}
+
+	for _, input := range []string{`{"error": "denied"}`, `[{"name": "a."}`, ``} {
+		err := decodeArray(strings.NewReader(input), func(PDNSSearchResponseItem) error { return nil })
+		if err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestGetPDNSRecordsLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		items := make([]PDNSSearchResponseItem, 100)
+		for i := range items {
+			items[i] = PDNSSearchResponseItem{Name: fmt.Sprintf("host%d.example.com.", i), Type: "A"}
+		}
+		json.NewEncoder(w).Encode(items)
+	}))
+	defer server.Close()
+
+	client := NewPDNSAPI(server.URL, "secret")
+	client.Limit = 5
+	records, err := GetPDNSRecords(context.Background(), client, []string{"host", "example.com."}, "all")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 5 {
+		t.Errorf("expected 5 records, got %d", len(records))
+	}
+}
+
+func TestMatchZones(t *testing.T) {
+	zones := []Zone{
+		{Name: "example.com.", Kind: "Native"},
+		{Name: "example.com.au.", Kind: "Master"},
+		{Name: "internal.example.org.", Kind: "Slave"},
+	}
+
+	tests := []struct {
+		pattern  string
+		expected int
+	}{
+		{"example", 3},
+		{"example.com", 2},
+		{"example.com.", 1},
+		{"*.example.org.", 1},
+		{"EXAMPLE.COM.", 1},
+		{"", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			matched, err := MatchZones(zones, tt.pattern)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(matched) != tt.expected {
+				t.Errorf("expected %d zones, got %v", tt.expected, matched)
+			}
+		})
+	}
+
+	if _, err := MatchZones(zones, "[example."); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+
+	if filtered := FilterZonesOnKind(zones, "slave"); len(filtered) != 1 || filtered[0].Name != "internal.example.org." {
+		t.Errorf("expected 1 slave zone, got %v", filtered)
+	}
+}
+
+func TestGetZone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/servers/localhost/zones/example.com." {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"name": "example.com.", "kind": "Native", "rrsets": [
+			{"name": "fw.example.com.", "type": "A", "ttl": 300, "records": [{"content": "10.0.0.1", "disabled": false}, {"content": "10.0.0.2", "disabled": true}],
+			 "comments": [{"content": "primary firewall", "account": "alice", "modified_at": 1714650000}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewPDNSAPI(server.URL, "secret")
+	zone, err := client.GetZone("example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(zone.RRSets) != 1 || len(zone.RRSets[0].Records) != 2 || !zone.RRSets[0].Records[1].Disabled {
+		t.Errorf("unexpected rrsets %+v", zone.RRSets)
+	}
+	if c := zone.RRSets[0].Comments; len(c) != 1 || c[0].Account != "alice" {
+		t.Errorf("unexpected comments %+v", c)
+	}
+
+	records := zone.Records()
+	if len(records) != 2 || records[0].Zone != "example.com." || records[0].Ttl != 300 || records[0].ObjectType != "record" {
+		t.Errorf("unexpected records %+v", records)
+	}
+
+	if _, err := client.GetZone("missing.com."); err == nil {
+		t.Error("expected error for missing zone")
+	}
+}
+
+func TestAddDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "example.com.", "rrsets": [
+			{"name": "fw.example.com.", "type": "A", "ttl": 300, "records": [{"content": "10.0.0.1", "disabled": false}, {"content": "10.0.0.2", "disabled": true}],
+			 "comments": [{"content": "primary firewall", "account": "alice"}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	records := []PDNSSearchResponseItem{
+		{Name: "fw.example.com.", Type: "A", Content: "10.0.0.1", ObjectType: "record", Zone: "example.com."},
+		{Name: "fw.example.com.", Type: "A", Content: "10.0.0.2", ObjectType: "record", Zone: "example.com."},
+		{Name: "example.com.", ObjectType: "zone", Zone: "example.com."},
+	}
+	if err := AddDetails(context.Background(), NewPDNSAPI(server.URL, "secret"), records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if records[0].Disabled || len(records[0].Comments) != 1 || records[0].Comments[0].Account != "alice" {
+		t.Errorf("unexpected details %+v", records[0])
+	}
+	if !records[1].Disabled {
+		t.Errorf("expected second record to be disabled")
+	}
+	if records[2].Comments != nil {
+		t.Errorf("expected no comments for zone, got %v", records[2].Comments)
+	}
+
+	if enabled := FilterRecordsOnDisabled(records, false); len(enabled) != 2 {
+		t.Errorf("expected 2 enabled records, got %d", len(enabled))
+	}
+	if disabled := FilterRecordsOnDisabled(records, true); len(disabled) != 1 || disabled[0].Content != "10.0.0.2" {
+		t.Errorf("expected the disabled record, got %v", disabled)
+	}
+}
+
+func TestAddDetailsLimit(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte(`{"name": "example.com.", "rrsets": []}`))
+	}))
+	defer server.Close()
+
+	var records []PDNSSearchResponseItem
+	for i := range 3 * maxZoneRequests {
+		records = append(records, PDNSSearchResponseItem{Name: "example.com.", ObjectType: "zone", Zone: fmt.Sprintf("zone%d.example.com.", i)})
+	}
+	if err := AddDetails(context.Background(), NewPDNSAPI(server.URL, "secret"), records); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m := maxRunning.Load(); m > maxZoneRequests {
+		t.Errorf("expected at most %d concurrent requests, got %d", maxZoneRequests, m)
+	}
+}
+
+func TestJoinComments(t *testing.T) {
+	records := []PDNSSearchResponseItem{
+		{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "10.0.0.1", ObjectType: "record"},
+		{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "10.0.0.2", ObjectType: "record"},
+		{Zone: "example.com.", Name: "fw.example.com.", Type: "TXT", Content: `"fw"`, ObjectType: "record"},
+		{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "primary firewall", ObjectType: "comment"},
+		{Zone: "example.com.", Name: "old.example.com.", Type: "A", Content: "decommissioned", ObjectType: "comment"},
+	}
+
+	joined := JoinComments(records)
+	if len(joined) != 4 {
+		t.Fatalf("expected 3 records and 1 comment, got %v", joined)
+	}
+	for _, r := range joined[:2] {
+		if len(r.Comments) != 1 || r.Comments[0].Content != "primary firewall" {
+			t.Errorf("expected comment on %s, got %v", r.Content, r.Comments)
+		}
+	}
+	if len(joined[2].Comments) != 0 {
+		t.Errorf("expected no comment on the TXT record, got %v", joined[2].Comments)
+	}
+	if joined[3].ObjectType != "comment" || joined[3].Content != "decommissioned" {
+		t.Errorf("expected the comment without records to be kept, got %v", joined[3])
+	}
+
+	// comments added by AddDetails are not duplicated
+	records[0].Comments = []Comment{{Content: "primary firewall", Account: "alice"}}
+	if joined := JoinComments(records); len(joined[0].Comments) != 1 || joined[0].Comments[0].Account != "alice" {
+		t.Errorf("expected the detailed comment only, got %v", joined[0].Comments)
+	}
+}
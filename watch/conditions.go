@@ -0,0 +1,41 @@
+package watch
+
+import "github.com/akquinet/pdnsgrep/pdns"
+
+// Exit codes of watch mode. Each exit condition has its own code so scripts
+// can tell which one ended the watch.
+const (
+	ExitFound   = 10
+	ExitGone    = 11
+	ExitChanged = 12
+	ExitTimeout = 124
+)
+
+// Conditions end watch mode once the watched results reach a given state.
+type Conditions struct {
+	// Found is met as soon as the search returns any record.
+	Found bool
+	// Gone is met as soon as the search returns no record.
+	Gone bool
+	// Changed is met on the first change after the initial poll.
+	Changed bool
+}
+
+// Enabled reports whether any exit condition is set.
+func (c Conditions) Enabled() bool {
+	return c.Found || c.Gone || c.Changed
+}
+
+// Check returns whether a condition is met by the result of a successful
+// poll and the exit code for it.
+func (c Conditions) Check(found []pdns.PDNSSearchResponseItem, changed bool) (bool, int) {
+	switch {
+	case c.Found && len(found) > 0:
+		return true, ExitFound
+	case c.Gone && len(found) == 0:
+		return true, ExitGone
+	case c.Changed && changed:
+		return true, ExitChanged
+	}
+	return false, 0
+}
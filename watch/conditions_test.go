@@ -0,0 +1,37 @@
+package watch
+
+import (
+	"testing"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+func TestConditionsCheck(t *testing.T) {
+	records := []pdns.PDNSSearchResponseItem{{Name: "fw.example.com.", Type: "A", Content: "10.0.0.1"}}
+
+	tests := []struct {
+		name       string
+		conditions Conditions
+		found      []pdns.PDNSSearchResponseItem
+		changed    bool
+		met        bool
+		code       int
+	}{
+		{"none", Conditions{}, records, true, false, 0},
+		{"found", Conditions{Found: true}, records, false, true, ExitFound},
+		{"not found yet", Conditions{Found: true}, nil, false, false, 0},
+		{"gone", Conditions{Gone: true}, nil, false, true, ExitGone},
+		{"not gone yet", Conditions{Gone: true}, records, true, false, 0},
+		{"changed", Conditions{Changed: true}, records, true, true, ExitChanged},
+		{"unchanged", Conditions{Changed: true}, records, false, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			met, code := tt.conditions.Check(tt.found, tt.changed)
+			if met != tt.met || code != tt.code {
+				t.Errorf("Check() = %v, %d, want %v, %d", met, code, tt.met, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package watch
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const defaultConcurrency = 4
+
+var objectTypes = []string{"all", "zone", "record", "comment"}
+
+// Query is a named search polled by the watch daemon.
+type Query struct {
+	Name       string        `mapstructure:"name"`
+	Terms      []string      `mapstructure:"terms"`
+	Type       string        `mapstructure:"type"`
+	ObjectType string        `mapstructure:"object-type"`
+	SortBy     string        `mapstructure:"sort-by"`
+	Interval   time.Duration `mapstructure:"interval"`
+
+	OnChangeExec    string `mapstructure:"on-change-exec"`
+	OnChangeWebhook string `mapstructure:"on-change-webhook"`
+	WebhookFormat   string `mapstructure:"webhook-format"`
+}
+
+// Config lists the queries of the watch daemon. At most Concurrency
+// searches run at the same time.
+type Config struct {
+	Concurrency int     `mapstructure:"concurrency"`
+	Queries     []Query `mapstructure:"queries"`
+}
+
+// LoadConfig reads a watch config file. Queries without an interval get
+// defaultInterval.
+func LoadConfig(path string, defaultInterval time.Duration) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("reading watch config: %w", err)
+	}
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("decoding watch config %s: %w", path, err)
+	}
+	if err := config.setDefaults(defaultInterval); err != nil {
+		return nil, fmt.Errorf("watch config %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+func (c *Config) setDefaults(defaultInterval time.Duration) error {
+	if len(c.Queries) == 0 {
+		return fmt.Errorf("no queries defined")
+	}
+	if c.Concurrency < 1 {
+		c.Concurrency = defaultConcurrency
+	}
+
+	names := make(map[string]bool)
+	for i := range c.Queries {
+		q := &c.Queries[i]
+		if q.Name == "" {
+			return fmt.Errorf("query %d has no name", i+1)
+		}
+		if names[q.Name] {
+			return fmt.Errorf("query %s is defined twice", q.Name)
+		}
+		names[q.Name] = true
+
+		if len(q.Terms) == 0 {
+			return fmt.Errorf("query %s has no terms", q.Name)
+		}
+		if q.ObjectType == "" {
+			q.ObjectType = "all"
+		}
+		if !slices.Contains(objectTypes, q.ObjectType) {
+			return fmt.Errorf("query %s has invalid object-type %s (valid options: all, zone, record, comment)", q.Name, q.ObjectType)
+		}
+		if err := ValidateWebhookFormat(q.WebhookFormat); err != nil {
+			return fmt.Errorf("query %s: %w", q.Name, err)
+		}
+		if q.Interval <= 0 {
+			q.Interval = defaultInterval
+		}
+	}
+	return nil
+}
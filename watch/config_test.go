@@ -0,0 +1,86 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "watch.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+concurrency: 2
+queries:
+  - name: firewalls
+    terms: ["*fw*"]
+    type: A
+    interval: 30s
+    on-change-webhook: https://hooks.example.com/fw
+    webhook-format: slack
+  - name: nameservers
+    terms: ["ns1", "ns2"]
+    object-type: record
+`)
+
+	config, err := LoadConfig(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Concurrency != 2 || len(config.Queries) != 2 {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+
+	fw := config.Queries[0]
+	if fw.Name != "firewalls" || fw.Type != "A" || fw.Interval != 30*time.Second || fw.ObjectType != "all" {
+		t.Errorf("unexpected firewalls query: %+v", fw)
+	}
+	if fw.OnChangeWebhook != "https://hooks.example.com/fw" || fw.WebhookFormat != "slack" {
+		t.Errorf("expected webhook settings, got %+v", fw)
+	}
+
+	ns := config.Queries[1]
+	if len(ns.Terms) != 2 || ns.ObjectType != "record" || ns.Interval != 5*time.Second {
+		t.Errorf("unexpected nameservers query: %+v", ns)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"no queries", "concurrency: 2\n"},
+		{"missing name", "queries:\n  - terms: [fw]\n"},
+		{"missing terms", "queries:\n  - name: fw\n"},
+		{"duplicate name", "queries:\n  - name: fw\n    terms: [fw]\n  - name: fw\n    terms: [fw2]\n"},
+		{"invalid object type", "queries:\n  - name: fw\n    terms: [fw]\n    object-type: rrset\n"},
+		{"invalid webhook format", "queries:\n  - name: fw\n    terms: [fw]\n    webhook-format: slak\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadConfig(writeConfig(t, tt.content), time.Second); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestLoadConfigDefaultConcurrency(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, "queries:\n  - name: fw\n    terms: [fw]\n"), time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Concurrency != defaultConcurrency {
+		t.Errorf("expected default concurrency %d, got %d", defaultConcurrency, config.Concurrency)
+	}
+}
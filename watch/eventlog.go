@@ -0,0 +1,168 @@
+package watch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	log "github.com/sirupsen/logrus"
+)
+
+// ChangeEvent is a single entry of the event log. Query names the watched
+// query of a watch config and is empty otherwise.
+type ChangeEvent struct {
+	Time  time.Time `json:"time"`
+	Query string    `json:"query,omitempty"`
+	misc.RecordChange
+}
+
+// EventLog appends change events as JSON lines to a file. Once the file
+// would grow beyond MaxSize it is rotated to Path.1, Path.2 and so on,
+// keeping at most MaxFiles rotated files.
+type EventLog struct {
+	Path     string
+	MaxSize  int64
+	MaxFiles int
+
+	mu sync.Mutex
+}
+
+// Write appends one event per change to the log. The log may be shared by
+// the watchers of several queries, query names the one the changes belong
+// to.
+func (l *EventLog) Write(at time.Time, query string, changes []misc.RecordChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var data []byte
+	for _, c := range changes {
+		line, err := json.Marshal(ChangeEvent{Time: at, Query: query, RecordChange: c})
+		if err != nil {
+			return fmt.Errorf("encoding event: %w", err)
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.MaxSize > 0 {
+		info, err := os.Stat(l.Path)
+		if err == nil && info.Size() > 0 && info.Size()+int64(len(data)) > l.MaxSize {
+			if err := l.rotate(); err != nil {
+				return fmt.Errorf("rotating %s: %w", l.Path, err)
+			}
+		}
+	}
+
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func rotatedName(base string, n int) string {
+	return fmt.Sprintf("%s.%d", base, n)
+}
+
+// rotate shifts the rotated files by one and moves the current log to
+// Path.1. The oldest file is dropped once MaxFiles is reached.
+func (l *EventLog) rotate() error {
+	keep := max(l.MaxFiles, 1)
+	if err := os.Remove(rotatedName(l.Path, keep)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for n := keep - 1; n >= 1; n-- {
+		err := os.Rename(rotatedName(l.Path, n), rotatedName(l.Path, n+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(l.Path, rotatedName(l.Path, 1))
+}
+
+// ReadEvents reads the event log at logPath including its rotated files,
+// oldest event first.
+func ReadEvents(logPath string) ([]ChangeEvent, error) {
+	files := []string{logPath}
+	for n := 1; ; n++ {
+		name := rotatedName(logPath, n)
+		if _, err := os.Stat(name); err != nil {
+			break
+		}
+		files = append([]string{name}, files...)
+	}
+
+	var events []ChangeEvent
+	for _, name := range files {
+		fileEvents, err := readEventFile(name)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, fileEvents...)
+	}
+	return events, nil
+}
+
+func readEventFile(name string) ([]ChangeEvent, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []ChangeEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event ChangeEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			log.Warnf("%s:%d: skipping invalid event: %v", name, line, err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+func normalizeName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// FilterEvents returns the events of the given record name. The name may
+// contain the wildcards * and ?, the trailing dot is optional.
+func FilterEvents(events []ChangeEvent, name string) []ChangeEvent {
+	pattern := normalizeName(name)
+	var filtered []ChangeEvent
+	for _, e := range events {
+		matched, err := path.Match(pattern, normalizeName(e.Name))
+		if err == nil && matched {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
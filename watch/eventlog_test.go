@@ -0,0 +1,99 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/misc"
+)
+
+func testChange(name string, ttl int) misc.RecordChange {
+	return misc.RecordChange{
+		Kind: misc.ChangeAdded, Zone: "example.com.", Name: name, Type: "A",
+		After: &misc.RRset{Zone: "example.com.", Name: name, Type: "A", Ttl: ttl, Contents: []string{"10.0.0.1"}},
+	}
+}
+
+func TestEventLogWriteAndRead(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "events.ndjson")
+	eventLog := &EventLog{Path: logPath}
+
+	at := time.Date(2024, 5, 2, 14, 30, 0, 0, time.UTC)
+	if err := eventLog.Write(at, "", []misc.RecordChange{testChange("a.example.com.", 300), testChange("b.example.com.", 300)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := eventLog.Write(at.Add(time.Minute), "firewalls", []misc.RecordChange{testChange("a.example.com.", 60)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	events, err := ReadEvents(logPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if events[0].Name != "a.example.com." || events[0].Kind != misc.ChangeAdded || !events[0].Time.Equal(at) {
+		t.Errorf("unexpected first event: %+v", events[0])
+	}
+	if events[0].Query != "" {
+		t.Errorf("expected no query in first event, got %q", events[0].Query)
+	}
+	if events[2].After.Ttl != 60 || events[2].Query != "firewalls" {
+		t.Errorf("expected ttl 60 of query firewalls in last event, got %+v", events[2])
+	}
+}
+
+func TestEventLogRotation(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "events.ndjson")
+	eventLog := &EventLog{Path: logPath, MaxSize: 1, MaxFiles: 2}
+
+	at := time.Date(2024, 5, 2, 14, 30, 0, 0, time.UTC)
+	for i := range 4 {
+		if err := eventLog.Write(at.Add(time.Duration(i)*time.Minute), "", []misc.RecordChange{testChange("a.example.com.", i)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, name := range []string{logPath, logPath + ".1", logPath + ".2"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("expected %s to exist", name)
+		}
+	}
+	if _, err := os.Stat(logPath + ".3"); err == nil {
+		t.Error("expected at most 2 rotated files")
+	}
+
+	events, err := ReadEvents(logPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events in log and rotated files, got %d", len(events))
+	}
+	for i, e := range events {
+		if e.After.Ttl != i+1 {
+			t.Errorf("expected events oldest first, got ttl %d at %d", e.After.Ttl, i)
+		}
+	}
+}
+
+func TestFilterEvents(t *testing.T) {
+	events := []ChangeEvent{
+		{RecordChange: testChange("fw-1.example.com.", 300)},
+		{RecordChange: testChange("fw-2.example.com.", 300)},
+		{RecordChange: testChange("ns1.example.com.", 300)},
+	}
+
+	if filtered := FilterEvents(events, "FW-1.example.com"); len(filtered) != 1 {
+		t.Errorf("expected 1 event for exact name, got %d", len(filtered))
+	}
+	if filtered := FilterEvents(events, "fw-*.example.com."); len(filtered) != 2 {
+		t.Errorf("expected 2 events for wildcard, got %d", len(filtered))
+	}
+	if filtered := FilterEvents(events, "mail.example.com."); len(filtered) != 0 {
+		t.Errorf("expected no events, got %d", len(filtered))
+	}
+}
@@ -0,0 +1,285 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultRetryDelay = time.Second
+	defaultTimeout    = 10 * time.Second
+)
+
+// WebhookFormats are the valid values of Hooks.WebhookFormat, an empty
+// format is the same as generic.
+var WebhookFormats = []string{"generic", "slack", "mattermost", "teams"}
+
+// ValidateWebhookFormat checks that format is one of WebhookFormats.
+func ValidateWebhookFormat(format string) error {
+	if format != "" && !slices.Contains(WebhookFormats, format) {
+		return fmt.Errorf("invalid webhook format: %s (valid options: %s)", format, strings.Join(WebhookFormats, ", "))
+	}
+	return nil
+}
+
+// Event is passed to the change hooks whenever watch mode detects changes.
+type Event struct {
+	Time time.Time `json:"time"`
+	// Name is the name of the query in the watch config.
+	Name    string              `json:"name,omitempty"`
+	Query   []string            `json:"query"`
+	Changes []misc.RecordChange `json:"changes"`
+}
+
+// label returns the name of the query, or its terms if it has none.
+func (e Event) label() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return strings.Join(e.Query, " ")
+}
+
+// Hooks notify about changes by running a command and/or posting to a
+// webhook. Failed notifications are retried with exponential backoff, each
+// attempt is canceled after Timeout.
+type Hooks struct {
+	// Exec is run by the shell and receives the event as JSON on stdin.
+	Exec string
+	// Webhook is the URL the event is posted to.
+	Webhook string
+	// WebhookFormat selects the payload: generic, slack, mattermost or teams.
+	WebhookFormat string
+	Retries       int
+	RetryDelay    time.Duration
+	Client        *http.Client
+	// Timeout limits each attempt, defaultTimeout if not set.
+	Timeout time.Duration
+}
+
+// Enabled reports whether any hook is configured.
+func (h Hooks) Enabled() bool {
+	return h.Exec != "" || h.Webhook != ""
+}
+
+// Notify runs all configured hooks for the event.
+func (h Hooks) Notify(ctx context.Context, event Event) error {
+	var errs []error
+	if h.Exec != "" {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("marshaling event: %w", err)
+		}
+		err = h.retry(ctx, func(ctx context.Context) error { return h.runExec(ctx, payload) })
+		if err != nil {
+			errs = append(errs, fmt.Errorf("on-change command: %w", err))
+		}
+	}
+	if h.Webhook != "" {
+		payload, err := webhookPayload(h.WebhookFormat, event)
+		if err != nil {
+			return err
+		}
+		err = h.retry(ctx, func(ctx context.Context) error { return h.postWebhook(ctx, payload) })
+		if err != nil {
+			errs = append(errs, fmt.Errorf("on-change webhook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// defaultQueueSize is the number of events a HookQueue buffers.
+const defaultQueueSize = 64
+
+// HookQueue runs the hooks in the background, so slow or retried hooks don't
+// delay the polls. Events arriving while the queue is full are dropped.
+type HookQueue struct {
+	hooks  Hooks
+	events chan Event
+	done   chan struct{}
+}
+
+// NewHookQueue starts delivering the events pushed to the queue to hooks.
+func NewHookQueue(hooks Hooks, size int) *HookQueue {
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	q := &HookQueue{hooks: hooks, events: make(chan Event, size), done: make(chan struct{})}
+	go q.deliver()
+	return q
+}
+
+func (q *HookQueue) deliver() {
+	defer close(q.done)
+	for event := range q.events {
+		if err := q.hooks.Notify(context.Background(), event); err != nil {
+			log.Errorf("%s: %v", event.label(), err)
+		}
+	}
+}
+
+// Push queues the event without waiting for its delivery. It reports false
+// if the queue is full and the event was dropped.
+func (q *HookQueue) Push(event Event) bool {
+	select {
+	case q.events <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close waits until the queued events are delivered.
+func (q *HookQueue) Close() {
+	close(q.events)
+	<-q.done
+}
+
+func (h Hooks) retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	delay := h.RetryDelay
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	var err error
+	for attempt := 0; attempt <= h.Retries; attempt++ {
+		if attempt > 0 {
+			log.Warnf("hook failed (attempt %d of %d): %v", attempt, h.Retries+1, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = fn(attemptCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (h Hooks) runExec(ctx context.Context, payload []byte) error {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, h.Exec)
+	cmd.Stdin = bytes.NewReader(payload)
+	// keep stdout free for the watch output
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	// don't wait for children of the shell still holding stdin after the
+	// shell was killed
+	cmd.WaitDelay = time.Second
+	return cmd.Run()
+}
+
+func (h Hooks) postWebhook(ctx context.Context, payload []byte) error {
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Webhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookPayload renders the event for the given chat system. The generic
+// format posts the event itself.
+func webhookPayload(format string, event Event) ([]byte, error) {
+	if err := ValidateWebhookFormat(format); err != nil {
+		return nil, err
+	}
+	lines := summaryLines(event)
+	var payload any
+	switch format {
+	case "", "generic":
+		payload = event
+	case "slack":
+		payload = map[string]string{
+			"text": lines[0] + "\n```\n" + strings.Join(lines[1:], "\n") + "\n```",
+		}
+	case "mattermost":
+		payload = map[string]string{
+			"username": "pdnsgrep",
+			"text":     lines[0] + "\n```\n" + strings.Join(lines[1:], "\n") + "\n```",
+		}
+	case "teams":
+		payload = map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  lines[0],
+			"title":    lines[0],
+			"text":     strings.Join(lines[1:], "\n\n"),
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling webhook payload: %w", err)
+	}
+	return data, nil
+}
+
+// summaryLines returns a title followed by one line per change.
+func summaryLines(event Event) []string {
+	lines := []string{fmt.Sprintf("pdnsgrep: %d change(s) for %s", len(event.Changes), event.label())}
+	for _, c := range event.Changes {
+		switch c.Kind {
+		case misc.ChangeAdded:
+			lines = append(lines, fmt.Sprintf("+ %s %s %d %s", c.Name, c.Type, c.After.Ttl, strings.Join(c.After.Contents, ", ")))
+		case misc.ChangeRemoved:
+			lines = append(lines, fmt.Sprintf("- %s %s %d %s", c.Name, c.Type, c.Before.Ttl, strings.Join(c.Before.Contents, ", ")))
+		case misc.ChangeModified:
+			line := fmt.Sprintf("~ %s %s", c.Name, c.Type)
+			if c.TTLChanged() {
+				line += fmt.Sprintf(" ttl %d -> %d", c.Before.Ttl, c.After.Ttl)
+			}
+			if c.ContentsChanged() {
+				line += fmt.Sprintf(" content %s -> %s", strings.Join(c.Before.Contents, ", "), strings.Join(c.After.Contents, ", "))
+			}
+			for _, comment := range c.RemovedComments() {
+				line += fmt.Sprintf(" comment -%q", comment.Content)
+			}
+			for _, comment := range c.AddedComments() {
+				line += fmt.Sprintf(" comment +%q", comment.Content)
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
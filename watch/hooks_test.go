@@ -0,0 +1,230 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/misc"
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+var testEvent = Event{
+	Time:  time.Date(2024, 5, 2, 14, 30, 0, 0, time.UTC),
+	Query: []string{"*fw*"},
+	Changes: []misc.RecordChange{
+		{
+			Kind: misc.ChangeModified, Zone: "example.com.", Name: "fw.example.com.", Type: "A",
+			Before: &misc.RRset{Name: "fw.example.com.", Type: "A", Ttl: 300, Contents: []string{"10.0.0.1"}},
+			After:  &misc.RRset{Name: "fw.example.com.", Type: "A", Ttl: 60, Contents: []string{"10.0.0.2"}},
+		},
+		{
+			Kind: misc.ChangeAdded, Zone: "example.com.", Name: "fw2.example.com.", Type: "A",
+			After: &misc.RRset{Name: "fw2.example.com.", Type: "A", Ttl: 300, Contents: []string{"10.0.0.3"}},
+		},
+	},
+}
+
+func TestSummaryLines(t *testing.T) {
+	lines := summaryLines(testEvent)
+	expected := []string{
+		"pdnsgrep: 2 change(s) for *fw*",
+		"~ fw.example.com. A ttl 300 -> 60 content 10.0.0.1 -> 10.0.0.2",
+		"+ fw2.example.com. A 300 10.0.0.3",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected summary:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestSummaryLinesName(t *testing.T) {
+	event := testEvent
+	event.Name = "firewalls"
+	if lines := summaryLines(event); lines[0] != "pdnsgrep: 2 change(s) for firewalls" {
+		t.Errorf("expected the query name in the title, got %q", lines[0])
+	}
+
+	data, err := webhookPayload("generic", event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"name":"firewalls"`) {
+		t.Errorf("expected the query name in the payload, got %s", data)
+	}
+}
+
+func TestSummaryLinesComments(t *testing.T) {
+	comment := pdns.Comment{Content: "INC-1234", Account: "alice"}
+	event := Event{
+		Query: []string{"*fw*"},
+		Changes: []misc.RecordChange{{
+			Kind: misc.ChangeModified, Zone: "example.com.", Name: "fw.example.com.", Type: "A",
+			Before: &misc.RRset{Name: "fw.example.com.", Type: "A", Ttl: 300, Contents: []string{"10.0.0.1"}},
+			After:  &misc.RRset{Name: "fw.example.com.", Type: "A", Ttl: 300, Contents: []string{"10.0.0.1"}, Comments: []pdns.Comment{comment}},
+		}},
+	}
+	lines := summaryLines(event)
+	if len(lines) != 2 || lines[1] != `~ fw.example.com. A comment +"INC-1234"` {
+		t.Errorf("expected comment detail, got %q", lines)
+	}
+}
+
+func TestWebhookPayload(t *testing.T) {
+	tests := []struct {
+		format string
+		key    string
+	}{
+		{"generic", "changes"},
+		{"slack", "text"},
+		{"mattermost", "text"},
+		{"teams", "@type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := webhookPayload(tt.format, testEvent)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var payload map[string]any
+			if err := json.Unmarshal(data, &payload); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if _, ok := payload[tt.key]; !ok {
+				t.Errorf("expected key %q in payload %s", tt.key, data)
+			}
+		})
+	}
+
+	if _, err := webhookPayload("irc", testEvent); err == nil {
+		t.Error("expected error for invalid format")
+	}
+}
+
+func TestNotifyWebhookRetries(t *testing.T) {
+	calls := 0
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	hooks := Hooks{Webhook: server.URL, WebhookFormat: "slack", Retries: 3, RetryDelay: time.Millisecond}
+	if err := hooks.Notify(context.Background(), testEvent); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+	if !strings.Contains(string(body), "fw2.example.com.") {
+		t.Errorf("expected change in payload, got %s", body)
+	}
+}
+
+func TestNotifyWebhookGivesUp(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	hooks := Hooks{Webhook: server.URL, Retries: 1, RetryDelay: time.Millisecond}
+	if err := hooks.Notify(context.Background(), testEvent); err == nil {
+		t.Error("expected error after all retries failed")
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestNotifyExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a POSIX shell")
+	}
+
+	out := filepath.Join(t.TempDir(), "event.json")
+	hooks := Hooks{Exec: "cat > " + out}
+	if err := hooks.Notify(context.Background(), testEvent); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("invalid JSON on stdin: %v", err)
+	}
+	if len(event.Changes) != 2 || event.Changes[0].Kind != misc.ChangeModified {
+		t.Errorf("unexpected event: %+v", event)
+	}
+}
+
+func TestNotifyExecTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a POSIX shell")
+	}
+
+	hooks := Hooks{Exec: "exec sleep 10", Retries: 1, RetryDelay: time.Millisecond, Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if err := hooks.Notify(context.Background(), testEvent); err == nil {
+		t.Error("expected error for hanging command")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected attempts to time out, took %s", elapsed)
+	}
+}
+
+func TestHookQueue(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	queue := NewHookQueue(Hooks{Webhook: server.URL}, 1)
+	// the first event is taken by the blocked delivery, the second fills the
+	// queue
+	if !queue.Push(testEvent) {
+		t.Fatal("expected first event to be queued")
+	}
+	deadline := time.Now().Add(time.Second)
+	for !queue.Push(testEvent) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected second event to be queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if queue.Push(testEvent) {
+		t.Error("expected event to be dropped while the queue is full")
+	}
+
+	close(release)
+	queue.Close()
+	if calls != 2 {
+		t.Errorf("expected 2 deliveries, got %d", calls)
+	}
+}
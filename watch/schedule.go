@@ -0,0 +1,56 @@
+package watch
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	// DefaultJitter spreads polls by up to 10% of the interval, so
+	// watchers started together don't hit the API at the same time.
+	DefaultJitter = 0.1
+	// maxBackoffFactor caps the delay after failed polls at this multiple
+	// of the interval.
+	maxBackoffFactor = 16
+)
+
+// Schedule computes the delay before the next poll.
+type Schedule struct {
+	Interval time.Duration
+	// Jitter randomly shortens or lengthens the delay by up to this
+	// fraction of the interval.
+	Jitter float64
+	// MaxBackoff caps the delay after failed polls, defaults to 16 times
+	// the interval.
+	MaxBackoff time.Duration
+
+	rand func() float64
+}
+
+// Next returns the delay before the next poll after the given number of
+// consecutive failed polls. The delay doubles with every failure.
+func (s Schedule) Next(failures int) time.Duration {
+	maxBackoff := s.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = maxBackoffFactor * s.Interval
+	}
+
+	delay := s.Interval
+	for range failures {
+		if delay >= maxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	delay = min(delay, maxBackoff)
+
+	if s.Jitter > 0 {
+		random := s.rand
+		if random == nil {
+			random = rand.Float64
+		}
+		// random in [0, 1) becomes a factor in [-jitter, jitter)
+		delay += time.Duration((random()*2 - 1) * s.Jitter * float64(s.Interval))
+	}
+	return max(delay, 0)
+}
@@ -0,0 +1,40 @@
+package watch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		failures int
+		expected time.Duration
+	}{
+		{"interval", Schedule{Interval: 10 * time.Second}, 0, 10 * time.Second},
+		{"backoff", Schedule{Interval: 10 * time.Second}, 2, 40 * time.Second},
+		{"default max backoff", Schedule{Interval: 10 * time.Second}, 20, 160 * time.Second},
+		{"max backoff", Schedule{Interval: 10 * time.Second, MaxBackoff: time.Minute}, 3, time.Minute},
+		{"jitter low", Schedule{Interval: 10 * time.Second, Jitter: 0.1, rand: func() float64 { return 0 }}, 0, 9 * time.Second},
+		{"jitter high", Schedule{Interval: 10 * time.Second, Jitter: 0.1, rand: func() float64 { return 0.75 }}, 0, 10500 * time.Millisecond},
+		{"jitter on backoff", Schedule{Interval: 10 * time.Second, Jitter: 0.1, rand: func() float64 { return 0 }}, 1, 19 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Next(tt.failures); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestScheduleJitterBounds(t *testing.T) {
+	s := Schedule{Interval: time.Second, Jitter: DefaultJitter}
+	for range 100 {
+		if d := s.Next(0); d < 900*time.Millisecond || d >= 1100*time.Millisecond {
+			t.Fatalf("expected delay within 10%% of the interval, got %s", d)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+// State is the last seen result set of a watch. It is saved to disk so a
+// restarted watch can report the changes made while it was not running.
+type State struct {
+	Query      []string                      `json:"query"`
+	ObjectType string                        `json:"object_type"`
+	Type       string                        `json:"type,omitempty"`
+	SavedAt    time.Time                     `json:"saved_at"`
+	Records    []pdns.PDNSSearchResponseItem `json:"records"`
+}
+
+// Matches reports whether the state was saved for the same search.
+func (s *State) Matches(query []string, objectType, rType string) bool {
+	return slices.Equal(s.Query, query) && s.ObjectType == objectType && s.Type == rType
+}
+
+// LoadState reads the state file at path. A missing file is not an error,
+// nil is returned instead.
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decoding state file %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// SaveState writes the state to path. The file is replaced atomically so an
+// interrupted write never leaves a truncated state behind.
+func SaveState(path string, state State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
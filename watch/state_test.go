@@ -0,0 +1,70 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akquinet/pdnsgrep/pdns"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state := State{
+		Query:      []string{"*fw*"},
+		ObjectType: "record",
+		SavedAt:    time.Date(2024, 5, 2, 14, 30, 0, 0, time.UTC),
+		Records: []pdns.PDNSSearchResponseItem{
+			{Zone: "example.com.", Name: "fw.example.com.", Type: "A", Content: "10.0.0.1", Ttl: 300, ObjectType: "record"},
+		},
+	}
+	if err := SaveState(path, state); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded == nil || len(loaded.Records) != 1 || loaded.Records[0].Content != "10.0.0.1" {
+		t.Errorf("unexpected state: %+v", loaded)
+	}
+	if !loaded.SavedAt.Equal(state.SavedAt) {
+		t.Errorf("expected saved at %v, got %v", state.SavedAt, loaded.SavedAt)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left, got %v", entries)
+	}
+}
+
+func TestLoadStateMissing(t *testing.T) {
+	state, err := LoadState(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || state != nil {
+		t.Errorf("expected nil state without error, got %v, %v", state, err)
+	}
+}
+
+func TestLoadStateInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte("{"), 0o600)
+	if _, err := LoadState(path); err == nil {
+		t.Error("expected error for invalid state file")
+	}
+}
+
+func TestStateMatches(t *testing.T) {
+	state := &State{Query: []string{"*fw*", "ns1"}, ObjectType: "all", Type: "A"}
+	if !state.Matches([]string{"*fw*", "ns1"}, "all", "A") {
+		t.Error("expected state to match the same search")
+	}
+	if state.Matches([]string{"*fw*"}, "all", "A") {
+		t.Error("expected state not to match other terms")
+	}
+	if state.Matches([]string{"*fw*", "ns1"}, "all", "") {
+		t.Error("expected state not to match another type filter")
+	}
+}